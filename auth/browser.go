@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// OpenBrowser opens the URL in the user's default browser.
+func OpenBrowser(url string) error {
+	fmt.Println("Opening URL in browser:", url) // Debugging: Print the URL
+
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = "powershell.exe"
+		args = []string{"Start-Process", url} // PowerShell on Windows
+	case "darwin":
+		cmd = "open"
+		args = []string{url}
+	case "linux":
+		// Detect if running inside WSL and use `wslview`
+		if isWSL() {
+			cmd = "wsl-open"
+		} else {
+			cmd = "xdg-open"
+		}
+		args = []string{url}
+	}
+
+	err := exec.Command(cmd, args...).Start()
+	if err != nil {
+		fmt.Println("Failed to open browser. Please manually visit:", url)
+	}
+	return err
+}
+
+// Detect if running inside WSL
+func isWSL() bool {
+	_, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop")
+	return err == nil
+
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// CallbackTimeout is how long the callback server waits for the user to finish authorizing.
+	CallbackTimeout = 5 * time.Minute
+
+	defaultCallbackPort = "8087"
+)
+
+// NewState returns a random value for the OAuth state parameter.
+func NewState() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CallbackServer is a short-lived local server that receives the OAuth redirect.
+// It uses its own ServeMux, so several flows can run in the same process.
+type CallbackServer struct {
+	server   *http.Server
+	listener net.Listener
+	state    string
+	result   chan callbackResult
+	once     sync.Once
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// StartCallbackServer listens on the host and port of redirectURI and waits for a redirect
+// carrying the expected state.
+func StartCallbackServer(redirectURI, state string) (*CallbackServer, error) {
+	parsedURL, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URI %q: %w", redirectURI, err)
+	}
+
+	port := parsedURL.Port()
+	if port == "" {
+		port = defaultCallbackPort
+	}
+	path := parsedURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(parsedURL.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("unable to start callback server: %w", err)
+	}
+
+	s := &CallbackServer{
+		listener: listener,
+		state:    state,
+		result:   make(chan callbackResult, 1),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, s.handleCallback)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	fmt.Println("Starting local server on http://" + listener.Addr().String())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.deliver(callbackResult{err: fmt.Errorf("callback server failed: %w", err)})
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *CallbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if authErr := query.Get("error"); authErr != "" {
+		http.Error(w, "Authorization failed: "+authErr, http.StatusBadRequest)
+		s.deliver(callbackResult{err: fmt.Errorf("authorization failed: %s", authErr)})
+		return
+	}
+
+	if query.Get("state") != s.state {
+		http.Error(w, "Authorization failed. State does not match.", http.StatusBadRequest)
+		s.deliver(callbackResult{err: errors.New("authorization failed: state mismatch")})
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		http.Error(w, "Authorization failed. No code received.", http.StatusBadRequest)
+		s.deliver(callbackResult{err: errors.New("authorization failed: no code received")})
+		return
+	}
+
+	fmt.Fprintln(w, "Authorization successful! You can close this window.")
+	s.deliver(callbackResult{code: code})
+}
+
+// deliver hands the first result to Wait; later callbacks are ignored.
+func (s *CallbackServer) deliver(result callbackResult) {
+	s.once.Do(func() {
+		s.result <- result
+	})
+}
+
+// Wait blocks until the redirect arrives, the timeout expires or ctx is done, then shuts the
+// server down.
+func (s *CallbackServer) Wait(ctx context.Context, timeout time.Duration) (string, error) {
+	defer s.Close()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-s.result:
+		return result.code, result.err
+	case <-timer.C:
+		return "", fmt.Errorf("timed out after %s waiting for authorization", timeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Addr returns the address the callback server listens on.
+func (s *CallbackServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close shuts the callback server down.
+func (s *CallbackServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// PromptForCode prints the authorization URL and reads either the full redirect URL the browser
+// ended up on or a bare authorization code from in.
+func PromptForCode(in io.Reader, authURL, state string) (string, error) {
+	fmt.Println("Open the following URL in a browser on any machine and authorize the app:")
+	fmt.Println(authURL)
+	fmt.Print("Paste the full redirect URL (or just the code): ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("unable to read authorization response: %w", err)
+	}
+	return ParseRedirectInput(line, state)
+}
+
+// ParseRedirectInput extracts the authorization code from a pasted redirect URL or bare code.
+// When the input is a URL its state must match and an error parameter is reported.
+func ParseRedirectInput(input, state string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("no authorization code entered")
+	}
+
+	// A bare code has no query string to look at
+	if !strings.Contains(input, "code=") && !strings.Contains(input, "error=") {
+		return input, nil
+	}
+
+	rawQuery := input
+	if i := strings.Index(input, "?"); i >= 0 {
+		rawQuery = input[i+1:]
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse redirect URL: %w", err)
+	}
+
+	if authErr := query.Get("error"); authErr != "" {
+		return "", fmt.Errorf("authorization failed: %s", authErr)
+	}
+	if query.Get("state") != state {
+		return "", errors.New("authorization failed: state mismatch")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("authorization failed: no code received")
+	}
+	return code, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"golang.org/x/oauth2"
+)
+
+// CachedTokenSource returns a token source for conf backed by cache. A cached token is reused and
+// refreshed when possible; the authorization flow only runs when there is no usable token.
+// service names the provider in log messages, e.g. "Spotify". ctx aborts the sign-in; the
+// returned token source keeps refreshing after ctx is cancelled, so a cancelled import can still
+// save what it has.
+func CachedTokenSource(ctx context.Context, conf *oauth2.Config, cache *TokenCache, service string, headless bool) (oauth2.TokenSource, error) {
+	refreshCtx := context.WithoutCancel(ctx)
+	token, err := cache.Load()
+	if err != nil {
+		fmt.Printf("Ignoring unreadable %s token cache: %v\n", service, err)
+	}
+	if token != nil {
+		tokenSource := cache.TokenSource(conf.TokenSource(refreshCtx, token))
+		// Make sure the cached token is still valid or can be refreshed
+		_, err := tokenSource.Token()
+		if err == nil {
+			fmt.Printf("Using cached %s token from %s\n", service, cache.Path())
+			return tokenSource, nil
+		}
+		fmt.Printf("Cached %s token can no longer be refreshed, re-authenticating: %v\n", service, err)
+	}
+
+	token, err = Authorize(ctx, conf, service, headless)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Save(token); err != nil {
+		fmt.Printf("Unable to cache %s token: %v\n", service, err)
+	}
+	return cache.TokenSource(conf.TokenSource(refreshCtx, token)), nil
+}
+
+// IsLocalRedirect reports whether the redirect URI points at a loopback address we can serve ourselves.
+func IsLocalRedirect(redirectURI string) bool {
+	parsedURL, err := url.Parse(redirectURI)
+	if err != nil || parsedURL.Scheme != "http" {
+		return false
+	}
+	switch parsedURL.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// UsePKCE reports whether the PKCE flow is used, which is the case when no client secret is configured.
+func UsePKCE(conf *oauth2.Config) bool {
+	return conf.ClientSecret == ""
+}
+
+// CodeSource obtains the authorization code after sending the user to authURL. state is the
+// value the provider has to echo back.
+type CodeSource func(ctx context.Context, authURL, state string) (string, error)
+
+// Authorize runs the interactive authorization code flow and exchanges the code for a token.
+// Headless mode, or a redirect URI we cannot serve locally, prompts for the redirect URL instead
+// of opening a browser.
+func Authorize(ctx context.Context, conf *oauth2.Config, service string, headless bool) (*oauth2.Token, error) {
+	if headless || !IsLocalRedirect(conf.RedirectURL) {
+		// After authorization, the provider will redirect to the redirect URI with a code
+		return AuthorizeWith(ctx, conf, service, func(ctx context.Context, authURL, state string) (string, error) {
+			return PromptForCode(os.Stdin, authURL, state)
+		})
+	}
+	return AuthorizeWith(ctx, conf, service, func(ctx context.Context, authURL, state string) (string, error) {
+		// if its local run local server
+		callbackServer, err := StartCallbackServer(conf.RedirectURL, state)
+		if err != nil {
+			return "", err
+		}
+		defer callbackServer.Close()
+
+		fmt.Printf("Opening browser for %s authentication...\n", service)
+		if err := OpenBrowser(authURL); err != nil {
+			fmt.Println("Failed to open browser. Please manually visit the URL below:")
+			fmt.Println(authURL)
+		}
+
+		// Wait for the authorization code to be received from the local server
+		return callbackServer.Wait(ctx, CallbackTimeout)
+	})
+}
+
+// AuthorizeWith runs the authorization code flow with getCode standing in for the user and
+// exchanges the code for a token. Without a client secret the PKCE flow is used.
+func AuthorizeWith(ctx context.Context, conf *oauth2.Config, service string, getCode CodeSource) (*oauth2.Token, error) {
+	authOpts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+	var exchangeOpts []oauth2.AuthCodeOption
+	if UsePKCE(conf) {
+		fmt.Printf("No %s client secret is set, using the PKCE authorization flow.\n", service)
+		verifier := oauth2.GenerateVerifier()
+		authOpts = append(authOpts, oauth2.S256ChallengeOption(verifier))
+		exchangeOpts = append(exchangeOpts, oauth2.VerifierOption(verifier))
+	}
+
+	state, err := NewState()
+	if err != nil {
+		return nil, err
+	}
+
+	// Redirect user to the authorization page
+	code, err := getCode(ctx, conf.AuthCodeURL(state, authOpts...), state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exchange the code for a token
+	return conf.Exchange(ctx, code, exchangeOpts...)
+}
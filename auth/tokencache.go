@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"yt-spotify/utils"
+
+	"golang.org/x/oauth2"
+)
+
+// appConfigDir is the directory created under the user config dir for cached credentials.
+const appConfigDir = "yt-spotify"
+
+// TokenCache stores an OAuth token, including its refresh token, as JSON in the user config directory.
+type TokenCache struct {
+	path string
+}
+
+// NewTokenCache returns a cache backed by fileName inside the user config directory.
+func NewTokenCache(fileName string) (*TokenCache, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("unable to locate user config directory: %w", err)
+	}
+	return &TokenCache{path: filepath.Join(dir, appConfigDir, fileName)}, nil
+}
+
+// Path returns the location of the cached token file.
+func (c *TokenCache) Path() string {
+	return c.path
+}
+
+// Load reads the cached token. It returns nil without an error when nothing is cached yet.
+func (c *TokenCache) Load() (*oauth2.Token, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("invalid token cache %s: %w", c.path, err)
+	}
+	return &token, nil
+}
+
+// Save writes the token to the cache file, readable by the current user only.
+func (c *TokenCache) Save(token *oauth2.Token) error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(c.path, data, 0600)
+}
+
+// Import copies a token JSON file, e.g. a cache file produced on another machine, into the cache.
+func (c *TokenCache) Import(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("invalid token file %s: %w", path, err)
+	}
+	if token.RefreshToken == "" && !token.Valid() {
+		return fmt.Errorf("token file %s has neither a refresh token nor a valid access token", path)
+	}
+	return c.Save(&token)
+}
+
+// Delete removes the cached token. Deleting a missing cache is not an error.
+func (c *TokenCache) Delete() error {
+	err := os.Remove(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// TokenSource wraps base so every refreshed token is written back to the cache.
+func (c *TokenCache) TokenSource(base oauth2.TokenSource) oauth2.TokenSource {
+	return &cachingTokenSource{base: base, cache: c}
+}
+
+// cachingTokenSource persists a token whenever the underlying source hands out a new one.
+type cachingTokenSource struct {
+	base  oauth2.TokenSource
+	cache *TokenCache
+
+	mu   sync.Mutex
+	last string
+}
+
+// Token implements oauth2.TokenSource.
+func (s *cachingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.last {
+		if err := s.cache.Save(token); err != nil {
+			fmt.Println("Unable to save refreshed token:", err)
+		}
+		s.last = token.AccessToken
+	}
+	return token, nil
+}
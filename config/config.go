@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
+	"time"
 	"yt-spotify/utils"
 )
 
@@ -13,12 +15,32 @@ type AppContext struct {
 	SpotifyClientID     string
 	SpotifyClientSecret string
 	SpotifyRedirectURI  string
-	Playlists           []string
+	Playlists           []PlaylistSource
+	PlaylistMode        string
 	PlayListsNameToSave string
 	MistralApiKey       string
 	ModelToUse          string
+	YouTubeClientID     string
+	YouTubeClientSecret string
+	YouTubeRedirectURI  string
+	Headless            bool
+	MatchThreshold      float64
+	DurationTolerance   time.Duration
+	SongsColumns        string
+	ExtractWorkers      int
+	SearchWorkers       int
+	ImportStateFile     string
 }
 
+// Default worker counts of the import pipeline.
+const (
+	defaultExtractWorkers = 2
+	defaultSearchWorkers  = 4
+)
+
+// defaultImportStateFile is where yt-spotify saves its progress unless IMPORT_STATE_FILE is set.
+const defaultImportStateFile = "import_state.json"
+
 var appContext *AppContext
 
 func GetConfig() (*AppContext, error) {
@@ -27,19 +49,66 @@ func GetConfig() (*AppContext, error) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	var playlists []string
+	var playlists []PlaylistSource
 	rawPlaylists := os.Getenv("PLAYLISTS")
 	if rawPlaylists != "" {
-		err := json.Unmarshal([]byte(rawPlaylists), &playlists)
+		var parsed []PlaylistSource
+		err := json.Unmarshal([]byte(rawPlaylists), &parsed)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing PLAYLISTS environment variable: %w", err)
 		}
+		// Skip placeholders such as the [""] of .env.example
+		for _, playlist := range parsed {
+			if playlist.ID != "" {
+				playlists = append(playlists, playlist)
+			}
+		}
 	}
 	var playListsName = os.Getenv("PLAYLIST_NAME_TO_SAVE")
 	if playListsName == "" {
 		playListsName = "Playlist"
 	}
 
+	playlistMode := os.Getenv("PLAYLIST_MODE")
+	switch playlistMode {
+	case "":
+		playlistMode = PlaylistModeSingle
+	case PlaylistModeSingle, PlaylistModePerSource:
+	default:
+		return nil, fmt.Errorf("PLAYLIST_MODE must be %q or %q, got %q", PlaylistModeSingle, PlaylistModePerSource, playlistMode)
+	}
+
+	// Zero leaves the threshold to the matcher's default, so it cannot be set explicitly
+	var matchThreshold float64
+	if rawThreshold := os.Getenv("MATCH_THRESHOLD"); rawThreshold != "" {
+		matchThreshold, err = strconv.ParseFloat(rawThreshold, 64)
+		if err != nil || matchThreshold <= 0 || matchThreshold > 1 {
+			return nil, fmt.Errorf("MATCH_THRESHOLD must be a number above 0 and at most 1, got %q; leave it empty for the default", rawThreshold)
+		}
+	}
+
+	var durationTolerance time.Duration
+	if rawTolerance := os.Getenv("DURATION_TOLERANCE"); rawTolerance != "" {
+		durationTolerance, err = time.ParseDuration(rawTolerance)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing DURATION_TOLERANCE environment variable: %w", err)
+		}
+	}
+
+	extractWorkers, err := workerCount("EXTRACT_WORKERS", defaultExtractWorkers)
+	if err != nil {
+		return nil, err
+	}
+	searchWorkers, err := workerCount("SEARCH_WORKERS", defaultSearchWorkers)
+	if err != nil {
+		return nil, err
+	}
+
+	importStateFile := os.Getenv("IMPORT_STATE_FILE")
+	if importStateFile == "" {
+		importStateFile = defaultImportStateFile
+	}
+
 	var model string
 	if os.Getenv("MODEL_TO_USE") == "mistral" {
 		model = utils.MISTRAL
@@ -54,11 +123,35 @@ func GetConfig() (*AppContext, error) {
 		SpotifyRedirectURI:  os.Getenv("SPOTIFY_REDIRECT_URI"),
 		PlayListsNameToSave: playListsName,
 		Playlists:           playlists,
+		PlaylistMode:        playlistMode,
 		MistralApiKey:       os.Getenv("MISTRAL_API_KEY"),
 		ModelToUse:          model,
+		YouTubeClientID:     os.Getenv("YOUTUBE_CLIENT_ID"),
+		YouTubeClientSecret: os.Getenv("YOUTUBE_CLIENT_SECRET"),
+		YouTubeRedirectURI:  os.Getenv("YOUTUBE_REDIRECT_URI"),
+		Headless:            os.Getenv("SPOTIFY_HEADLESS") == "true",
+		MatchThreshold:      matchThreshold,
+		DurationTolerance:   durationTolerance,
+		SongsColumns:        os.Getenv("SONGS_COLUMNS"),
+		ExtractWorkers:      extractWorkers,
+		SearchWorkers:       searchWorkers,
+		ImportStateFile:     importStateFile,
 	}, nil
 }
 
+// workerCount reads a positive worker count from the environment variable name.
+func workerCount(name string, fallback int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", name, raw)
+	}
+	return n, nil
+}
+
 func GetAppContext() *AppContext {
 	if appContext == nil {
 		panic("AppContext is not initialized. Call LoadConfig() first.")
@@ -72,7 +165,6 @@ func LoadConfig() {
 	if err != nil {
 		fmt.Println("Failed to load config:", err)
 		panic("Failed to load config.")
-		return
 	}
 
 }
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Access modes of a YouTube playlist source.
+const (
+	// AuthAPIKey reads the playlist with YOUTUBE_API_KEY, which only works for public and unlisted playlists.
+	AuthAPIKey = "apikey"
+	// AuthOAuth reads the playlist as the signed in YouTube user, which private playlists require.
+	AuthOAuth = "oauth"
+)
+
+// Playlist modes of PLAYLIST_MODE.
+const (
+	// PlaylistModeSingle adds every source to the one PLAYLIST_NAME_TO_SAVE playlist.
+	PlaylistModeSingle = "single"
+	// PlaylistModePerSource gives each source its own Spotify playlist, named after the source
+	// or after its configured name.
+	PlaylistModePerSource = "per-playlist"
+)
+
+// specialPlaylists are the per-user lists that can only be read with OAuth: Liked videos and Watch later.
+var specialPlaylists = map[string]bool{"LL": true, "WL": true}
+
+// PlaylistSource is a YouTube playlist to import. In PLAYLISTS it is either a bare playlist ID
+// or an object such as {"id": "PL...", "auth": "oauth", "name": "Road Trip"}.
+type PlaylistSource struct {
+	// ID is a playlist ID, or a playlist, video or channel link or handle as youtube.ParseSource accepts.
+	ID string `json:"id"`
+	// Auth is AuthAPIKey, AuthOAuth or empty for the default, see AppContext.PlaylistAuth.
+	Auth string `json:"auth,omitempty"`
+	// Name is the Spotify playlist to fill in the per-playlist mode, empty to use the YouTube title.
+	Name string `json:"name,omitempty"`
+}
+
+func (p *PlaylistSource) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*p = PlaylistSource{ID: strings.TrimSpace(id)}
+		return nil
+	}
+
+	// A local type without the UnmarshalJSON method to decode the object form
+	type source PlaylistSource
+	var decoded source
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("playlist must be an ID or an object with an id: %w", err)
+	}
+	decoded.ID = strings.TrimSpace(decoded.ID)
+	decoded.Name = strings.TrimSpace(decoded.Name)
+	decoded.Auth = strings.ToLower(strings.TrimSpace(decoded.Auth))
+	if decoded.Auth != "" && decoded.Auth != AuthAPIKey && decoded.Auth != AuthOAuth {
+		return fmt.Errorf("playlist %s: auth must be %q or %q, got %q", decoded.ID, AuthAPIKey, AuthOAuth, decoded.Auth)
+	}
+	*p = PlaylistSource(decoded)
+	return nil
+}
+
+// PlaylistAuth returns how a playlist is read. Liked videos and Watch later always need OAuth;
+// other playlists use their own setting, else the API key when one is configured, else OAuth.
+func (c *AppContext) PlaylistAuth(source PlaylistSource) string {
+	switch {
+	case specialPlaylists[strings.ToUpper(source.ID)]:
+		return AuthOAuth
+	case source.Auth != "":
+		return source.Auth
+	case c.YouTubeAPIKey != "":
+		return AuthAPIKey
+	}
+	return AuthOAuth
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"yt-spotify/config"
+	"yt-spotify/songs"
+	"yt-spotify/spotify"
+)
+
+// unsafeFileChars matches characters that should not end up in an exported file name.
+var unsafeFileChars = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]+`)
+
+// ExportPlaylist writes a Spotify playlist, given by name, ID or link, to a file.
+// The format (xspf, jspf, csv, json or m3u) is taken from the format argument, else from the
+// output file extension, else XSPF.
+func ExportPlaylist(ctx context.Context, nameOrID, format, outPath string) {
+	appCtx := config.GetAppContext()
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(outPath)), ".")
+	}
+	format = strings.ToLower(format)
+	if format == "m3u8" {
+		format = "m3u"
+	}
+	if format == "" {
+		format = "xspf"
+	}
+	write, found := exportWriters[format]
+	if !found {
+		log.Fatalf("Unsupported export format '%s'. Use one of: xspf, jspf, csv, json, m3u.", format)
+	}
+
+	spotifyClient := authenticateSpotify(ctx, appCtx)
+
+	playlist, err := spotify.FindPlaylist(ctx, spotifyClient, nameOrID)
+	if err != nil {
+		log.Fatalf("Unable to find Spotify playlist: %v", err)
+	}
+
+	tracks, err := spotify.FetchPlaylistTracks(ctx, spotifyClient, playlist.ID)
+	if err != nil {
+		log.Fatalf("Unable to fetch tracks of '%s': %v", playlist.Name, err)
+	}
+
+	if outPath == "" {
+		outPath = strings.TrimSpace(unsafeFileChars.ReplaceAllString(playlist.Name, "_")) + "." + format
+	}
+	if err := exportFile(outPath, write, playlist.Name, trackEntries(tracks)); err != nil {
+		log.Fatalf("Unable to write %s: %v", outPath, err)
+	}
+
+	fmt.Printf("Exported %d tracks of '%s' to %s\n", len(tracks), playlist.Name, outPath)
+}
+
+// exportFile writes the entries to path with write. The file is removed again when writing or
+// closing it fails, so a truncated export never looks like a finished one.
+func exportFile(path string, write func(w io.Writer, title string, entries []songs.Entry) error, title string, entries []songs.Entry) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(file, title, entries)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// exportWriters are the supported export formats.
+var exportWriters = map[string]func(w io.Writer, title string, entries []songs.Entry) error{
+	"xspf": songs.WriteXSPF,
+	"jspf": songs.WriteJSPF,
+	"json": songs.WriteJSON,
+	"m3u":  songs.WriteM3U,
+	"csv": func(w io.Writer, _ string, entries []songs.Entry) error {
+		return songs.WriteCSV(w, entries)
+	},
+}
+
+// trackEntries converts Spotify playlist tracks into songs entries for the file writers.
+func trackEntries(tracks []spotify.Track) []songs.Entry {
+	entries := make([]songs.Entry, 0, len(tracks))
+	for _, track := range tracks {
+		entries = append(entries, songs.Entry{
+			Kind:      songs.SpotifyTrack,
+			Raw:       track.URL(),
+			Title:     track.Name,
+			Artist:    strings.Join(track.Artists, ", "),
+			Artists:   track.Artists,
+			Album:     track.Album,
+			SpotifyID: track.ID,
+			ISRC:      track.ISRC,
+			Duration:  track.Duration,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"yt-spotify/spotify"
+	"yt-spotify/state"
+	"yt-spotify/youtube"
+)
+
+// Target is the Spotify playlist a source is imported into.
+type Target struct {
+	ID    string
+	Index *spotify.PlaylistIndex
+}
+
+// Job is a YouTube item on its way through the import pipeline.
+type Job struct {
+	Target *Target
+	Item   *youtube.PlaylistItem
+	// Source and VideoID key the progress of the item in the import state, Video holds it
+	Source  string
+	VideoID string
+	Video   state.Video
+	// URIs are the Spotify tracks matched for the item and not yet in the target playlist
+	URIs []string
+}
+
+// Imported tells whether an earlier run already added the item to its target playlist.
+func (j *Job) Imported() bool {
+	return j.Video.AddedTo == j.Target.ID
+}
+
+// Record updates the saved progress of the item. Items without a video ID are not tracked.
+func (j *Job) Record(store *state.Store, f func(*state.Video)) {
+	if j.VideoID != "" {
+		store.Update(j.Source, j.VideoID, f)
+	}
+}
+
+// MatchTrack finds the track on Spotify and returns its URI, unless there is no match or
+// the playlist already contains it. The outcome of the search is recorded in track, and a track
+// searched by an earlier run reuses its match.
+func MatchTrack(ctx context.Context, spotifyClient *http.Client, matcher spotify.Matcher, playlistIndex *spotify.PlaylistIndex, track *state.Track) (string, bool) {
+	if !track.Searched {
+		query := spotify.TrackQuery{Title: track.Title, Artist: track.Artist, Duration: track.Duration}
+		match, err := spotify.FindTrack(ctx, spotifyClient, matcher, query)
+		var noMatch *spotify.NoMatchError
+		if errors.As(err, &noMatch) && track.Unsplit != "" && ctx.Err() == nil {
+			log.Printf("%v, searching for '%s' as a title instead", err, track.Unsplit)
+			match, err = spotify.FindTrack(ctx, spotifyClient, matcher, spotify.TrackQuery{Title: track.Unsplit, Duration: track.Duration})
+		}
+		if ctx.Err() != nil {
+			return "", false
+		}
+		if err != nil {
+			log.Printf("Unable to find track '%s' by '%s' on Spotify: %v", track.Title, track.Artist, err)
+			// Only remember a definite miss, failed requests are retried on the next run
+			track.Searched = errors.As(err, &noMatch)
+			return "", false
+		}
+		track.Searched, track.SpotifyID = true, match.Candidate.ID
+		fmt.Printf("Matched '%s' by '%s' to '%s' by '%s' (score %.2f)\n", track.Title, track.Artist,
+			match.Candidate.Name, strings.Join(match.Candidate.Artists, ", "), match.Score)
+	} else if track.SpotifyID == "" {
+		return "", false
+	}
+
+	if !playlistIndex.TryAdd(track.SpotifyID) {
+		fmt.Printf("🟢 '%s' by '%s' already exists in playlist, skipping addition.\n", track.Title, track.Artist)
+		return "", false
+	}
+	return spotify.TrackURI(track.SpotifyID), true
+}
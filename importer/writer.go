@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"yt-spotify/state"
+)
+
+// writeBatchSize is how many matched tracks of a playlist are collected before they are added.
+const writeBatchSize = 100
+
+// stateSaveInterval is how many written items go by before the import state is saved again.
+const stateSaveInterval = 25
+
+// AddFunc appends the URIs to a Spotify playlist in order and returns how many of them, counted
+// from the start, were added.
+type AddFunc func(ctx context.Context, playlistID string, uris []string) int
+
+// Writer adds matched tracks to their Spotify playlists in the order it receives them, in
+// batches, and records in the import state which items were added. It is only used from the
+// pipeline's writer goroutine.
+type Writer struct {
+	ctx   context.Context
+	add   AddFunc
+	store *state.Store
+	// pending holds the URIs not yet added, by playlist ID; order lists the playlist IDs as first seen
+	pending map[string][]string
+	order   []string
+	// waiting holds the items with URIs pending, or queued behind them, by playlist ID
+	waiting map[string][]*Job
+	// flushed marks playlists that already had a batch added
+	flushed map[string]bool
+
+	// Items, Resumed and Added count the YouTube items written, those an earlier run already
+	// imported and the tracks added, for the summary
+	Items   int
+	Resumed int
+	Added   int
+}
+
+// NewWriter returns a writer that adds tracks with add and records progress in store. ctx is
+// passed to add.
+func NewWriter(ctx context.Context, add AddFunc, store *state.Store) *Writer {
+	return &Writer{
+		ctx:     ctx,
+		add:     add,
+		store:   store,
+		pending: make(map[string][]string),
+		waiting: make(map[string][]*Job),
+		flushed: make(map[string]bool),
+	}
+}
+
+// Write queues the tracks of a job and adds them once a full batch is collected.
+func (w *Writer) Write(job *Job) {
+	w.Items++
+	if job.Imported() {
+		w.Resumed++
+		fmt.Printf("🟢 '%s' was imported by an earlier run, skipping.\n", job.Item.Snippet.Title)
+		return
+	}
+
+	playlistID := job.Target.ID
+	if _, seen := w.pending[playlistID]; !seen {
+		w.order = append(w.order, playlistID)
+	}
+	w.pending[playlistID] = append(w.pending[playlistID], job.URIs...)
+	w.waiting[playlistID] = append(w.waiting[playlistID], job)
+
+	if len(w.pending[playlistID]) >= writeBatchSize {
+		w.flush(playlistID)
+	} else if w.Items%stateSaveInterval == 0 {
+		w.save()
+	}
+}
+
+// FlushAll adds the tracks still queued for every playlist.
+func (w *Writer) FlushAll() {
+	for _, playlistID := range w.order {
+		if len(w.pending[playlistID]) == 0 && w.flushed[playlistID] {
+			w.markAdded(playlistID, 0)
+			continue
+		}
+		w.flush(playlistID)
+	}
+}
+
+// flush adds the queued tracks of a playlist and marks the items whose tracks all made it as
+// imported. Tracks that were not added stay queued, ahead of the next batch.
+func (w *Writer) flush(playlistID string) {
+	uris := w.pending[playlistID]
+	added := w.add(w.ctx, playlistID, uris)
+	w.Added += added
+	w.pending[playlistID] = uris[added:]
+	w.markAdded(playlistID, added)
+	w.flushed[playlistID] = true
+	w.save()
+}
+
+// markAdded records the waiting items of a playlist covered by the first added URIs as imported.
+// Items with a track whose search failed stay open, so the next run searches it again.
+func (w *Writer) markAdded(playlistID string, added int) {
+	waiting := w.waiting[playlistID]
+	for len(waiting) > 0 && len(waiting[0].URIs) <= added {
+		job := waiting[0]
+		added -= len(job.URIs)
+		if job.Video.Searched() {
+			job.Record(w.store, func(video *state.Video) { video.AddedTo = playlistID })
+		}
+		waiting = waiting[1:]
+	}
+	// Part of the next item's tracks may have been added, skip them
+	if len(waiting) > 0 && added > 0 {
+		waiting[0].URIs = waiting[0].URIs[added:]
+	}
+	w.waiting[playlistID] = waiting
+}
+
+func (w *Writer) save() {
+	if err := w.store.Save(); err != nil {
+		log.Printf("Unable to save import state to %s: %v", w.store.Path(), err)
+	}
+}
@@ -1,34 +1,127 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"yt-spotify/config"
+	"yt-spotify/spotify"
+	"yt-spotify/youtube"
+
+	youtubeV3 "google.golang.org/api/youtube/v3"
 )
 
 func main() {
 	//init config
 	config.LoadConfig()
+	appCtx := config.GetAppContext()
+
+	var command string
+	var args []string
 	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "yt-spotify":
-			YouTubeToSpotify()
-		case "songs-spotify":
-			SongsToSpotify()
-		default:
-			fmt.Println("Invalid argument. Use 'yt-spotify' or 'songs-spotify'.")
-		}
+		command = os.Args[1]
+		args = os.Args[2:]
 	} else {
-		fmt.Println("Choose an option: \n1. Convert YouTube playlist to Spotify (yt-to-spotify)\n2. Convert songs from list to Spotify (songs-to-spotify)")
+		fmt.Println("Choose an option: \n1. Convert YouTube playlist to Spotify (yt-to-spotify)\n2. Convert songs from list to Spotify (songs-to-spotify)\n3. Convert Spotify playlist to YouTube (spotify-to-yt)")
 		var choice int
 		fmt.Scanln(&choice)
 		switch choice {
 		case 1:
-			YouTubeToSpotify()
+			command = "yt-spotify"
 		case 2:
-			SongsToSpotify()
+			command = "songs-spotify"
+		case 3:
+			command = "spotify-yt"
 		default:
 			fmt.Println("Invalid choice")
+			return
+		}
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.BoolVar(&appCtx.Headless, "headless", appCtx.Headless, "authenticate without a browser by pasting the redirect URL or code")
+	flags.StringVar(&appCtx.SongsColumns, "columns", appCtx.SongsColumns, "CSV/TSV column mapping for songs-spotify, e.g. title=Song,artist=Artist,album=Album,isrc=ISRC")
+	flags.IntVar(&appCtx.ExtractWorkers, "extract-workers", appCtx.ExtractWorkers, "concurrent LLM extractions for yt-spotify")
+	flags.IntVar(&appCtx.SearchWorkers, "search-workers", appCtx.SearchWorkers, "concurrent Spotify searches for yt-spotify")
+	exportFormat := flags.String("format", "", "export format (xspf, jspf, csv, json, m3u), defaults to the --out extension or xspf")
+	exportOut := flags.String("out", "", "export file, defaults to the playlist name")
+	fresh := flags.Bool("fresh", false, "ignore the progress saved by earlier yt-spotify runs and start over")
+	youtubePlaylistName := flags.String("name", "", "YouTube playlist to fill for spotify-yt, defaults to the Spotify playlist name")
+	flags.Parse(args)
+
+	// The first Ctrl-C cancels ctx so the commands can stop and save what they have; after that
+	// the default handling is restored and a second Ctrl-C quits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		fmt.Println("\nInterrupted, finishing up and saving progress. Press Ctrl-C again to quit immediately.")
+	}()
+
+	switch command {
+	case "yt-spotify":
+		YouTubeToSpotify(ctx, *fresh)
+	case "songs-spotify":
+		SongsToSpotify(ctx)
+	case "spotify-yt":
+		if flags.NArg() > 1 {
+			log.Fatal("Usage: spotify-yt [--name youtube playlist] [spotify playlist name, ID or link]")
+		}
+		SpotifyToYouTube(ctx, flags.Arg(0), *youtubePlaylistName)
+	case "export":
+		if flags.NArg() != 1 {
+			log.Fatal("Usage: export [--format xspf|jspf|csv|json|m3u] [--out file] <playlist name, ID or link>")
 		}
+		ExportPlaylist(ctx, flags.Arg(0), *exportFormat, *exportOut)
+	case "logout":
+		if err := spotify.Logout(); err != nil {
+			log.Fatalf("Unable to remove cached Spotify token: %v", err)
+		}
+		if err := youtube.Logout(); err != nil {
+			log.Fatalf("Unable to remove cached YouTube token: %v", err)
+		}
+	case "import-token":
+		if flags.NArg() != 1 {
+			log.Fatal("Usage: import-token <token.json>")
+		}
+		if err := spotify.ImportToken(flags.Arg(0)); err != nil {
+			log.Fatalf("Unable to import Spotify token: %v", err)
+		}
+	default:
+		fmt.Println("Invalid argument. Use 'yt-spotify', 'songs-spotify', 'spotify-yt', 'export', 'logout' or 'import-token'.")
+	}
+}
+
+// authenticateSpotify returns a Spotify client using the credentials from the app config.
+func authenticateSpotify(ctx context.Context, appCtx *config.AppContext) *http.Client {
+	spotifyClient, err := spotify.Authenticate(ctx, spotify.AuthOptions{
+		ClientID:     appCtx.SpotifyClientID,
+		ClientSecret: appCtx.SpotifyClientSecret,
+		RedirectURI:  appCtx.SpotifyRedirectURI,
+		Headless:     appCtx.Headless,
+	})
+	if err != nil {
+		log.Fatalf("Unable to authenticate with Spotify: %v", err)
+	}
+	return spotifyClient
+}
+
+// authenticateYouTube returns a YouTube service acting as the signed in user, using the OAuth
+// client from the app config.
+func authenticateYouTube(ctx context.Context, appCtx *config.AppContext) *youtubeV3.Service {
+	youtubeService, err := youtube.Authenticate(ctx, youtube.AuthOptions{
+		ClientID:     appCtx.YouTubeClientID,
+		ClientSecret: appCtx.YouTubeClientSecret,
+		RedirectURI:  appCtx.YouTubeRedirectURI,
+		Headless:     appCtx.Headless,
+	})
+	if err != nil {
+		log.Fatalf("Unable to authenticate with YouTube: %v", err)
 	}
+	return youtubeService
 }
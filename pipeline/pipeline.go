@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Limits sets the number of workers of each stage.
+type Limits struct {
+	// Extractors is the number of concurrent extraction calls, e.g. LLM requests.
+	Extractors int
+	// Searchers is the number of concurrent search calls, e.g. Spotify searches.
+	Searchers int
+}
+
+// window returns how many values may be in flight between the source and the writer.
+// Bounding it keeps one slow value from letting the values behind it pile up in memory.
+func (l Limits) window() int {
+	return 2 * (l.Extractors + l.Searchers)
+}
+
+type sequenced[T any] struct {
+	seq   int
+	value T
+}
+
+// Run passes every value the source emits through extract and then search, each run by its
+// own pool of workers, and hands the results to write in the order the source emitted them.
+// write is only called from one goroutine. emit blocks while the pipeline is full, so a slow
+// stage holds back the source instead of queueing without bound. Run returns once every
+// emitted value has been written.
+//
+// When ctx is done emit returns false and the source should stop. Values still in flight are
+// dropped rather than written, since their stages may have been cut short, and Run returns after
+// writing the values that completed in order before that.
+func Run[In, Mid, Out any](ctx context.Context, limits Limits, source func(emit func(In) bool), extract func(In) Mid, search func(Mid) Out, write func(Out)) {
+	limits.Extractors = max(limits.Extractors, 1)
+	limits.Searchers = max(limits.Searchers, 1)
+
+	window := make(chan struct{}, limits.window())
+	inputs := make(chan sequenced[In], limits.Extractors)
+	extracted := make(chan sequenced[Mid], limits.Searchers)
+	results := make(chan sequenced[Out], limits.Searchers)
+
+	go func() {
+		defer close(inputs)
+		seq := 0
+		source(func(value In) bool {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case inputs <- sequenced[In]{seq, value}:
+			case <-ctx.Done():
+				return false
+			}
+			seq++
+			return true
+		})
+	}()
+	runWorkers(ctx, limits.Extractors, inputs, extracted, extract)
+	runWorkers(ctx, limits.Searchers, extracted, results, search)
+
+	// Results arrive in completion order; hold them back until their predecessors are written
+	pending := make(map[int]Out)
+	next := 0
+	for result := range results {
+		pending[result.seq] = result.value
+		for {
+			value, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			write(value)
+			next++
+			<-window
+		}
+	}
+}
+
+// runWorkers starts n workers applying f to the values of in and closes out when all are done.
+// Once ctx is done the remaining values are drained without calling f.
+func runWorkers[In, Out any](ctx context.Context, n int, in <-chan sequenced[In], out chan<- sequenced[Out], f func(In) Out) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for value := range in {
+				if ctx.Err() != nil {
+					continue
+				}
+				result := f(value.value)
+				if ctx.Err() != nil {
+					continue
+				}
+				out <- sequenced[Out]{value.seq, result}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
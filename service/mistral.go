@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -38,7 +39,7 @@ func NewMistralService(config *config.AppContext) (MistralService, error) {
 }
 
 // ExtractSongArtist calls Mistral AI API to get the song and artist.
-func (m *MistralServiceImpl) ExtractSongArtist(videoTitle string) (string, string, error) {
+func (m *MistralServiceImpl) ExtractSongArtist(ctx context.Context, videoTitle string) (string, string, error) {
 	// Define the prompt
 	prompt := fmt.Sprintf("Extract the song title and artist from this YouTube video title: '%s'. Return it in the format: Song: <song_name>, Artist: <artist_name>.", videoTitle)
 
@@ -52,7 +53,7 @@ func (m *MistralServiceImpl) ExtractSongArtist(videoTitle string) (string, strin
 	})
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", m.apiURL, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", m.apiURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return "", "", err
 	}
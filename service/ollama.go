@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,7 +14,7 @@ import (
 // OllamaService defines the interface for the Ollama API interaction.
 type OllamaService interface {
 	AiService
-	IsOllamaAvailable() bool
+	IsOllamaAvailable(ctx context.Context) bool
 }
 
 // OllamaServiceImpl implements OllamaService.
@@ -43,9 +44,13 @@ func NewOllamaService() OllamaService {
 }
 
 // IsOllamaAvailable checks if the Ollama API is running.
-func (o *OllamaServiceImpl) IsOllamaAvailable() bool {
+func (o *OllamaServiceImpl) IsOllamaAvailable(ctx context.Context) bool {
 	client := http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get("http://localhost:11434/api/tags")
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://localhost:11434/api/tags", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -54,7 +59,7 @@ func (o *OllamaServiceImpl) IsOllamaAvailable() bool {
 }
 
 // ExtractSongArtist calls Ollama and extracts the song and artist from the response.
-func (o *OllamaServiceImpl) ExtractSongArtist(videoTitle string) (string, string, error) {
+func (o *OllamaServiceImpl) ExtractSongArtist(ctx context.Context, videoTitle string) (string, string, error) {
 	// Define the prompt
 	prompt := fmt.Sprintf("Extract the song title and artist from this YouTube video title: '%s'. Return it in the format: Song: <song_name>, Artist: <artist_name>.", videoTitle)
 
@@ -65,7 +70,12 @@ func (o *OllamaServiceImpl) ExtractSongArtist(videoTitle string) (string, string
 	})
 
 	// Send HTTP request to Ollama
-	resp, err := http.Post(o.apiURL, "application/json", bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", o.apiURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Println("Error sending request to Ollama:", err)
 		return "", "", err
@@ -1,5 +1,7 @@
 package service
 
+import "context"
+
 type AiService interface {
-	ExtractSongArtist(videoTitle string) (string, string, error)
+	ExtractSongArtist(ctx context.Context, videoTitle string) (string, string, error)
 }
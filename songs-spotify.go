@@ -1,55 +1,121 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"yt-spotify/config"
+	"yt-spotify/songs"
 	"yt-spotify/spotify"
 )
 
-func SongsToSpotify() {
+// SongsToSpotify imports the songs files in inputFiles into Spotify. When ctx is cancelled the
+// lookups stop and the tracks found so far are still added.
+func SongsToSpotify(ctx context.Context) {
 	appCtx := config.GetAppContext()
 
+	columnMapping, err := songs.ParseColumnMapping(appCtx.SongsColumns)
+	if err != nil {
+		log.Fatalf("Invalid column mapping: %v", err)
+	}
+
 	files, err := os.ReadDir("inputFiles")
 	if err != nil {
 		log.Fatalf("Unable to read input directory: %v", err)
 	}
 
-	var songLines []string
+	var entries []songs.Entry
 	for _, file := range files {
-		if strings.HasPrefix(file.Name(), "songs") {
-			data, err := os.ReadFile("inputFiles/" + file.Name())
-			if err != nil {
-				log.Printf("Unable to read file %s: %v", file.Name(), err)
-				continue
-			}
-			songLines = append(songLines, strings.Split(string(data), "\n")...)
+		if file.IsDir() || !songs.IsSongsFile(file.Name()) {
+			continue
+		}
+		fileEntries, err := songs.LoadFile(filepath.Join("inputFiles", file.Name()), columnMapping)
+		if err != nil {
+			log.Printf("Unable to read file %s: %v", file.Name(), err)
+			continue
 		}
+		entries = append(entries, fileEntries...)
 	}
 
-	spotifyClient, err := spotify.Authenticate(appCtx.SpotifyClientID, appCtx.SpotifyClientSecret, appCtx.SpotifyRedirectURI)
+	spotifyClient := authenticateSpotify(ctx, appCtx)
+
+	spotifyPlaylistID, err := spotify.CheckOrCreatePlaylist(ctx, spotifyClient, appCtx.PlayListsNameToSave, "")
 	if err != nil {
-		log.Fatalf("Unable to authenticate with Spotify: %v", err)
+		log.Fatalf("Unable to create Spotify playlist: %v", err)
 	}
 
-	spotifyPlaylistID, err := spotify.CheckOrCreatePlaylist(spotifyClient, appCtx.PlayListsNameToSave)
+	playlistIndex, err := spotify.LoadPlaylistIndex(ctx, spotifyClient, spotifyPlaylistID)
 	if err != nil {
-		log.Fatalf("Unable to create Spotify playlist: %v", err)
+		log.Fatalf("Unable to load Spotify playlist tracks: %v", err)
 	}
 
-	for _, line := range songLines {
-		trackID, err := spotify.SearchTrack(spotifyClient, string(line), "")
+	matcher := spotify.NewMatcher(appCtx.MatchThreshold, appCtx.DurationTolerance)
+	var uris []string
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, adding the tracks found so far.")
+			break
+		}
+		trackID, err := resolveEntry(ctx, spotifyClient, matcher, entry)
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, adding the tracks found so far.")
+			break
+		}
 		if err != nil {
-			log.Printf("Unable to find track '%s' on Spotify: %v", line, err)
+			log.Printf("Unable to find track '%s' on Spotify: %v", entry.Raw, err)
 			continue
 		}
-		err = spotify.AddTrackToPlaylist(spotifyClient, spotifyPlaylistID, trackID)
-		if err != nil {
-			log.Printf("Unable to add track '%s' to Spotify playlist: %v", line, err)
+		if !playlistIndex.TryAdd(trackID) {
+			fmt.Printf("🟢 '%s' already exists in playlist, skipping addition.\n", entry.Raw)
 			continue
 		}
-		fmt.Printf("Added '%s' to Spotify playlist\n", line)
+		uris = append(uris, spotify.TrackURI(trackID))
 	}
+
+	// Not cancelled with ctx, so the tracks found before an interruption are still added
+	flushTracks(context.WithoutCancel(ctx), spotifyClient, spotifyPlaylistID, uris)
+}
+
+// resolveEntry returns the Spotify track ID for a songs file entry. Track links are used as is,
+// ISRC codes are looked up exactly and only free text, or an ISRC Spotify does not know about,
+// goes through the fuzzy search.
+func resolveEntry(ctx context.Context, spotifyClient *http.Client, matcher spotify.Matcher, entry songs.Entry) (string, error) {
+	var match *spotify.Match
+	var err error
+
+	switch entry.Kind {
+	case songs.SpotifyTrack:
+		fmt.Printf("Using Spotify track '%s' from '%s'\n", entry.SpotifyID, entry.Raw)
+		return entry.SpotifyID, nil
+	case songs.ISRC:
+		match, err = spotify.FindTrackByISRC(ctx, spotifyClient, entry.ISRC)
+		if err != nil && entry.Title != "" {
+			log.Printf("%v, searching for '%s' instead", err, entry.Raw)
+			match, err = spotify.FindTrack(ctx, spotifyClient, matcher, entryQuery(entry))
+		}
+	default:
+		match, err = spotify.FindTrack(ctx, spotifyClient, matcher, entryQuery(entry))
+		var noMatch *spotify.NoMatchError
+		if errors.As(err, &noMatch) && entry.Unsplit != "" {
+			log.Printf("%v, searching for '%s' as a title instead", err, entry.Unsplit)
+			match, err = spotify.FindTrack(ctx, spotifyClient, matcher, spotify.TrackQuery{Title: entry.Unsplit, Album: entry.Album, Duration: entry.Duration})
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Matched '%s' to '%s' by '%s' (%s, score %.2f)\n", entry.Raw,
+		match.Candidate.Name, strings.Join(match.Candidate.Artists, ", "), entry.Kind, match.Score)
+	return match.Candidate.ID, nil
+}
+
+// entryQuery builds the Spotify search query for an entry.
+func entryQuery(entry songs.Entry) spotify.TrackQuery {
+	return spotify.TrackQuery{Title: entry.Title, Artist: entry.Artist, Album: entry.Album, Duration: entry.Duration}
 }
@@ -0,0 +1,229 @@
+package songs
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fields a CSV/TSV column can be mapped to.
+const (
+	FieldTitle   = "title"
+	FieldArtist  = "artist"
+	FieldAlbum   = "album"
+	FieldISRC    = "isrc"
+	FieldSpotify = "spotify"
+)
+
+// headerAliases are the header names recognized for each field when no mapping is given.
+var headerAliases = map[string][]string{
+	FieldTitle:   {"title", "track", "track name", "song", "song name", "name"},
+	FieldArtist:  {"artist", "artists", "artist name", "artist name(s)", "performer"},
+	FieldAlbum:   {"album", "album name", "release"},
+	FieldISRC:    {"isrc"},
+	FieldSpotify: {"spotify", "spotify url", "spotify uri", "uri", "url", "track uri"},
+}
+
+// defaultColumnOrder is used for files without a header row and without a mapping.
+var defaultColumnOrder = []string{FieldTitle, FieldArtist, FieldAlbum, FieldISRC}
+
+// ColumnMapping maps a field (title, artist, album, isrc, spotify) to a column header name
+// or to a 1-based column number.
+type ColumnMapping map[string]string
+
+// ParseColumnMapping parses a mapping such as "title=Song,artist=Performer,isrc=3".
+func ParseColumnMapping(spec string) (ColumnMapping, error) {
+	mapping := ColumnMapping{}
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, found := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+		if !found || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=column", pair)
+		}
+		if _, known := headerAliases[field]; !known {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// ReadDelimited reads CSV (comma ',') or TSV (comma '\t') rows into entries.
+// A header row is detected when it names the mapped columns or, without a mapping, any known field.
+func ReadDelimited(r io.Reader, comma rune, mapping ColumnMapping) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns, hasHeader, err := resolveColumns(rows[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+	if hasHeader {
+		rows = rows[1:]
+	}
+
+	var entries []Entry
+	for _, row := range rows {
+		entry, ok := rowEntry(row, columns)
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// resolveColumns maps fields to 0-based column indexes and reports whether the first row is a header.
+func resolveColumns(firstRow []string, mapping ColumnMapping) (map[string]int, bool, error) {
+	headers := make(map[string]int, len(firstRow))
+	for i, cell := range firstRow {
+		headers[strings.ToLower(strings.TrimSpace(cell))] = i
+	}
+
+	columns := make(map[string]int)
+	hasHeader := false
+
+	if len(mapping) > 0 {
+		for field, column := range mapping {
+			if index, err := strconv.Atoi(column); err == nil {
+				if index < 1 {
+					return nil, false, fmt.Errorf("column number for %s must start at 1", field)
+				}
+				columns[field] = index - 1
+				continue
+			}
+			index, found := headers[strings.ToLower(column)]
+			if !found {
+				return nil, false, fmt.Errorf("column %q for %s not found in header", column, field)
+			}
+			columns[field] = index
+			hasHeader = true
+		}
+		// A header row may still be present when only column numbers were mapped
+		if !hasHeader {
+			hasHeader = looksLikeHeader(headers)
+		}
+		return columns, hasHeader, nil
+	}
+
+	for field, aliases := range headerAliases {
+		for _, alias := range aliases {
+			if index, found := headers[alias]; found {
+				columns[field] = index
+				hasHeader = true
+				break
+			}
+		}
+	}
+	if hasHeader {
+		_, hasTitle := columns[FieldTitle]
+		_, hasISRC := columns[FieldISRC]
+		_, hasSpotify := columns[FieldSpotify]
+		if !hasTitle && !hasISRC && !hasSpotify {
+			return nil, false, errors.New("header has no title, isrc or spotify column, use a column mapping")
+		}
+		return columns, true, nil
+	}
+
+	for i, field := range defaultColumnOrder {
+		columns[field] = i
+	}
+	return columns, false, nil
+}
+
+func looksLikeHeader(headers map[string]int) bool {
+	for _, aliases := range headerAliases {
+		for _, alias := range aliases {
+			if _, found := headers[alias]; found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rowEntry builds an entry from a row, skipping rows without anything to look up.
+func rowEntry(row []string, columns map[string]int) (Entry, bool) {
+	cell := func(field string) string {
+		index, found := columns[field]
+		if !found || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	entry := Entry{
+		Kind:   FreeText,
+		Raw:    strings.Join(row, " "),
+		Title:  cell(FieldTitle),
+		Artist: cell(FieldArtist),
+		Album:  cell(FieldAlbum),
+	}
+
+	if spotifyRef := cell(FieldSpotify); spotifyRef != "" {
+		if classified := ClassifyLine(spotifyRef); classified.Kind == SpotifyTrack {
+			entry.Kind, entry.SpotifyID = SpotifyTrack, classified.SpotifyID
+		}
+	}
+	if isrc := cell(FieldISRC); isrc != "" && entry.Kind == FreeText {
+		if classified := ClassifyLine(isrc); classified.Kind == ISRC {
+			entry.Kind, entry.ISRC = ISRC, classified.ISRC
+		}
+	}
+
+	if entry.Kind == FreeText && entry.Title == "" {
+		return Entry{}, false
+	}
+	if entry.Title != "" {
+		entry.Raw = entry.Title
+		if entry.Artist != "" {
+			entry.Raw = entry.Artist + " - " + entry.Title
+		}
+	}
+	return entry, true
+}
+
+// csvExportHeader names the exported columns so that ReadDelimited recognizes them again.
+var csvExportHeader = []string{"Track Name", "Artist Name(s)", "Album Name", "ISRC", "Duration (ms)", "Spotify URL"}
+
+// WriteCSV writes the entries as CSV with a header row, one track per row.
+// Multiple artists are joined with ", ".
+func WriteCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		row := []string{
+			entry.Title,
+			strings.Join(entry.artistList(), ", "),
+			entry.Album,
+			entry.ISRC,
+			strconv.FormatInt(entry.Duration.Milliseconds(), 10),
+			entry.SpotifyURL(),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
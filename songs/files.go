@@ -0,0 +1,51 @@
+package songs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsSongsFile reports whether a file in the input folder should be imported: plain text lists
+// whose name starts with "songs", every spreadsheet export and every player playlist.
+func IsSongsFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".tsv", ".m3u", ".m3u8", ".pls", ".xspf", ".jspf":
+		return true
+	}
+	return strings.HasPrefix(name, "songs")
+}
+
+// LoadFile reads the entries of a songs file, choosing the parser from the file extension.
+func LoadFile(path string, mapping ColumnMapping) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadDelimited(file, ',', mapping)
+	case ".tsv":
+		return ReadDelimited(file, '\t', mapping)
+	case ".m3u", ".m3u8":
+		return ReadM3U(file)
+	case ".pls":
+		return ReadPLS(file)
+	case ".xspf":
+		return ReadXSPF(file)
+	case ".jspf":
+		return ReadJSPF(file)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if entry, ok := ParseLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,41 @@
+package songs
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonExport is the layout written by WriteJSON.
+type jsonExport struct {
+	Name   string      `json:"name"`
+	Tracks []jsonTrack `json:"tracks"`
+}
+
+type jsonTrack struct {
+	Name       string   `json:"name"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	ISRC       string   `json:"isrc"`
+	DurationMs int64    `json:"duration_ms"`
+	SpotifyURL string   `json:"spotify_url"`
+}
+
+// WriteJSON writes the entries as an indented JSON document with one object per track,
+// keeping every artist as a separate list element.
+func WriteJSON(w io.Writer, title string, entries []Entry) error {
+	export := jsonExport{Name: title, Tracks: make([]jsonTrack, 0, len(entries))}
+	for _, entry := range entries {
+		export.Tracks = append(export.Tracks, jsonTrack{
+			Name:       entry.Title,
+			Artists:    entry.artistList(),
+			Album:      entry.Album,
+			ISRC:       entry.ISRC,
+			DurationMs: entry.Duration.Milliseconds(),
+			SpotifyURL: entry.SpotifyURL(),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
@@ -0,0 +1,217 @@
+package songs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trackNumber matches a leading track number in file names such as "01 - Title" or "3. Title".
+var trackNumber = regexp.MustCompile(`^(?:\d{1,3}\s*[-.)_]\s*|0\d\s+)`)
+
+// ReadM3U reads an M3U or M3U8 playlist. Artist and title come from the #EXTINF line
+// preceding each file, or from the file path when there is none. Spotify track links
+// as locations are used directly.
+func ReadM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var extinf, album string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			extinf = strings.TrimPrefix(line, "#EXTINF:")
+			continue
+		case strings.HasPrefix(line, "#EXTALB:"):
+			album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		entry, ok := entryFromPlaylist(extinf, line)
+		if ok && album != "" {
+			entry.Album = album
+		}
+		extinf, album = "", ""
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// entryFromPlaylist prefers the display title of a playlist entry and falls back to its path,
+// which also fills in the artist when the display title does not name one.
+func entryFromPlaylist(extinf, location string) (Entry, bool) {
+	entry, ok := entryFromExtinf(extinf)
+	if classified := ClassifyLine(location); classified.Kind == SpotifyTrack {
+		if !ok {
+			return classified, true
+		}
+		entry.Kind, entry.SpotifyID = SpotifyTrack, classified.SpotifyID
+		return entry, true
+	}
+
+	fromPath, pathOK := entryFromPath(location)
+	if !ok {
+		return fromPath, pathOK
+	}
+	if entry.Artist == "" && pathOK {
+		entry.Artist, entry.Album = fromPath.Artist, fromPath.Album
+	}
+	return entry, true
+}
+
+// entryFromExtinf parses the part after "#EXTINF:", e.g. `215 tvg-id="x",Artist - Title`.
+func entryFromExtinf(extinf string) (Entry, bool) {
+	attributes, display, found := strings.Cut(extinf, ",")
+	display = strings.TrimSpace(display)
+	if !found || display == "" {
+		return Entry{}, false
+	}
+
+	entry := Entry{Kind: FreeText, Raw: display}
+	entry.setTitle(display)
+	if fields := strings.Fields(attributes); len(fields) > 0 {
+		entry.Duration = parseSeconds(fields[0])
+	}
+	return entry, true
+}
+
+// ReadPLS reads a PLS playlist, using TitleN when present and FileN otherwise.
+func ReadPLS(r io.Reader) ([]Entry, error) {
+	files := map[int]string{}
+	titles := map[int]string{}
+	lengths := map[int]string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if n, ok := plsIndex(key, "file"); ok {
+			files[n] = value
+		} else if n, ok := plsIndex(key, "title"); ok {
+			titles[n] = value
+		} else if n, ok := plsIndex(key, "length"); ok {
+			lengths[n] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	numbers := make([]int, 0, len(files))
+	for n := range files {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var entries []Entry
+	for _, n := range numbers {
+		entry, ok := entryFromPlaylist(","+titles[n], files[n])
+		if ok {
+			entry.Duration = parseSeconds(lengths[n])
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// plsIndex returns N for keys such as "file3" given the prefix "file".
+func plsIndex(key, prefix string) (int, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+	return n, err == nil
+}
+
+// entryFromPath derives artist and title from a media path such as "Artist/Album/01 - Title.mp3".
+// A file name of the form "Artist - Title" takes precedence over the folder names.
+func entryFromPath(location string) (Entry, bool) {
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" && len(parsed.Scheme) > 1 {
+		location = parsed.Path
+	} else if unescaped, err := url.PathUnescape(location); err == nil {
+		location = unescaped
+	}
+
+	location = strings.ReplaceAll(location, "\\", "/")
+	parts := strings.Split(strings.Trim(location, "/"), "/")
+	base := parts[len(parts)-1]
+	base = strings.TrimSpace(strings.TrimSuffix(base, path.Ext(base)))
+	base = strings.TrimSpace(trackNumber.ReplaceAllString(base, ""))
+	if base == "" {
+		return Entry{}, false
+	}
+
+	entry := Entry{Kind: FreeText, Raw: location}
+	entry.setTitle(base)
+	if entry.Artist == "" {
+		// Artist/Album/Track layout, or Artist/Track when there is no album folder
+		switch {
+		case len(parts) >= 3:
+			entry.Artist, entry.Album = parts[len(parts)-3], parts[len(parts)-2]
+		case len(parts) == 2:
+			entry.Artist = parts[0]
+		}
+	}
+	return entry, true
+}
+
+// WriteM3U writes the entries as an extended M3U playlist. Spotify tracks are written as
+// their open.spotify.com links, other entries as their raw line.
+func WriteM3U(w io.Writer, title string, entries []Entry) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if title != "" {
+		fmt.Fprintf(&b, "#PLAYLIST:%s\n", title)
+	}
+	for _, entry := range entries {
+		location := entry.SpotifyURL()
+		if location == "" {
+			location = entry.Raw
+		}
+
+		seconds := int64(-1)
+		if entry.Duration > 0 {
+			seconds = int64(entry.Duration.Round(time.Second) / time.Second)
+		}
+		display := entry.Title
+		if artists := entry.artistList(); len(artists) > 0 {
+			display = strings.Join(artists, ", ") + " - " + entry.Title
+		}
+
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", seconds, display)
+		if entry.Album != "" {
+			fmt.Fprintf(&b, "#EXTALB:%s\n", entry.Album)
+		}
+		b.WriteString(location + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// parseSeconds parses a length in seconds, treating -1 and garbage as unknown.
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,168 @@
+package songs
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EntryKind tells how an entry should be looked up on Spotify.
+type EntryKind int
+
+const (
+	// FreeText entries go through the fuzzy Spotify search.
+	FreeText EntryKind = iota
+	// SpotifyTrack entries already carry a Spotify track ID.
+	SpotifyTrack
+	// ISRC entries are looked up by their exact recording code, falling back to
+	// a search when they also carry a title.
+	ISRC
+)
+
+func (k EntryKind) String() string {
+	switch k {
+	case SpotifyTrack:
+		return "spotify track"
+	case ISRC:
+		return "isrc"
+	}
+	return "free text"
+}
+
+// Entry is a single track read from a songs file.
+type Entry struct {
+	Kind EntryKind
+	// Raw is the line the entry was read from.
+	Raw    string
+	Title  string
+	Artist string
+	// Artists lists every credited artist when the source provides them separately;
+	// Artist then holds them joined.
+	Artists   []string
+	Album     string
+	SpotifyID string
+	ISRC      string
+	// Duration is the length of the track, zero when unknown.
+	Duration time.Duration
+	// Unsplit is the whole line when Title and Artist come from a " by " split, which may be
+	// part of the title as in "Stand by Me". It is searched when the split finds nothing.
+	Unsplit string
+}
+
+// SpotifyURL returns the open.spotify.com link of a Spotify track entry, or "" for other entries.
+func (e Entry) SpotifyURL() string {
+	if e.SpotifyID == "" {
+		return ""
+	}
+	return "https://open.spotify.com/track/" + e.SpotifyID
+}
+
+// artistList returns the separate artists of an entry, falling back to its single artist field.
+func (e Entry) artistList() []string {
+	if len(e.Artists) > 0 {
+		return e.Artists
+	}
+	if e.Artist != "" {
+		return []string{e.Artist}
+	}
+	return []string{}
+}
+
+var (
+	// spotifyTrackURL matches open.spotify.com track links, including localized ones like /intl-de/track/...
+	spotifyTrackURL = regexp.MustCompile(`^(?:https?://)?open\.spotify\.com/(?:intl-[a-z]{2}(?:-[a-z]{2})?/)?track/([0-9A-Za-z]{22})(?:[/?#].*)?$`)
+	spotifyTrackURI = regexp.MustCompile(`^spotify:track:([0-9A-Za-z]{22})$`)
+	// isrcCode matches a 12 character ISRC, optionally hyphenated (CC-XXX-YY-NNNNN) and prefixed with "isrc:".
+	isrcCode = regexp.MustCompile(`(?i)^(?:isrc:\s*)?([A-Z]{2})-?([A-Z0-9]{3})-?(\d{2})-?(\d{5})$`)
+)
+
+var (
+	// numbering matches list numbering such as "01." or "3)" at the start of a line.
+	numbering = regexp.MustCompile(`^\d{1,3}[.)]\s*`)
+	// bracketedTimestamp matches timestamps such as "[03:45]" or "(1:02:03)".
+	bracketedTimestamp = regexp.MustCompile(`[\[(]\d{1,2}:\d{2}(?::\d{2})?[\])]`)
+)
+
+// separators split "Artist - Title" style lines, tried in order.
+var separators = []string{"\t", " - ", " – ", " — ", " | ", "|"}
+
+// titleBy splits "Title by Artist" lines. It is weaker than the separators, as "by" is also a
+// common word in titles, and is not used when the line has a dash or colon.
+const titleBy = " by "
+
+// strongSeparator matches a colon or a dash set apart from a word, unlike the one in "a-ha".
+var strongSeparator = regexp.MustCompile(`:|\s[-–—]|[-–—]\s`)
+
+// ParseLine parses a songs file line into an entry. Blank lines and lines starting with '#'
+// are skipped, in which case ok is false.
+func ParseLine(line string) (entry Entry, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return Entry{}, false
+	}
+
+	trimmed = bracketedTimestamp.ReplaceAllString(trimmed, "")
+	trimmed = strings.TrimSpace(numbering.ReplaceAllString(trimmed, ""))
+	if trimmed == "" {
+		return Entry{}, false
+	}
+
+	entry = ClassifyLine(trimmed)
+	entry.Raw = strings.TrimSpace(line)
+	if entry.Kind == FreeText {
+		entry.setTitle(trimmed)
+	}
+	return entry, true
+}
+
+// setTitle sets the title and artist of an entry from a line, remembering the whole line when
+// the split is a guess.
+func (e *Entry) setTitle(line string) {
+	var byArtist bool
+	e.Title, e.Artist, byArtist = splitArtistTitle(line)
+	if byArtist {
+		e.Unsplit = line
+	}
+}
+
+// splitArtistTitle splits a line on the first known separator, and only without one of them on
+// the last " by ", so "Stand by Me by Ben E. King" keeps its title. Without any the whole line
+// is the title. byArtist reports a " by " split.
+func splitArtistTitle(line string) (title, artist string, byArtist bool) {
+	for _, separator := range separators {
+		before, after, found := strings.Cut(line, separator)
+		before, after = strings.TrimSpace(before), strings.TrimSpace(after)
+		if found && before != "" && after != "" {
+			return after, before, false
+		}
+	}
+
+	if !strongSeparator.MatchString(line) {
+		if i := strings.LastIndex(line, titleBy); i >= 0 {
+			before, after := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+len(titleBy):])
+			if before != "" && after != "" {
+				return before, after, true
+			}
+		}
+	}
+	return line, "", false
+}
+
+// ClassifyLine decides whether a line holds a Spotify track link, an ISRC code or free text.
+func ClassifyLine(line string) Entry {
+	trimmed := strings.TrimSpace(line)
+	entry := Entry{Kind: FreeText, Raw: line, Title: trimmed}
+
+	if matches := spotifyTrackURL.FindStringSubmatch(trimmed); matches != nil {
+		entry.Kind, entry.SpotifyID = SpotifyTrack, matches[1]
+	} else if matches := spotifyTrackURI.FindStringSubmatch(trimmed); matches != nil {
+		entry.Kind, entry.SpotifyID = SpotifyTrack, matches[1]
+	} else if matches := isrcCode.FindStringSubmatch(trimmed); matches != nil {
+		entry.Kind, entry.ISRC = ISRC, strings.ToUpper(strings.Join(matches[1:], ""))
+	}
+
+	if entry.Kind != FreeText {
+		entry.Title = ""
+	}
+	return entry
+}
@@ -0,0 +1,193 @@
+package songs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// xspfNamespace is the XML namespace of XSPF version 1.
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// xspfPlaylist is the XML Shareable Playlist Format document.
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Locations   []string `xml:"location" json:"location,omitempty"`
+	Identifiers []string `xml:"identifier" json:"identifier,omitempty"`
+	Title       string   `xml:"title,omitempty" json:"title,omitempty"`
+	Creator     string   `xml:"creator,omitempty" json:"creator,omitempty"`
+	Album       string   `xml:"album,omitempty" json:"album,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `xml:"duration,omitempty" json:"duration,omitempty"`
+}
+
+// jspfDocument is the JSON flavour of XSPF.
+type jspfDocument struct {
+	Playlist struct {
+		Title  string      `json:"title,omitempty"`
+		Tracks []jspfTrack `json:"track"`
+	} `json:"playlist"`
+}
+
+// jspfTrack accepts location and identifier both as a list, as the spec says, and as a plain
+// string, as several exporters write them.
+type jspfTrack struct {
+	xspfTrack
+	Locations   stringList `json:"location,omitempty"`
+	Identifiers stringList `json:"identifier,omitempty"`
+}
+
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ReadXSPF reads the tracks of an XSPF playlist.
+func ReadXSPF(r io.Reader) ([]Entry, error) {
+	var playlist xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, fmt.Errorf("invalid XSPF playlist: %w", err)
+	}
+	return xspfEntries(playlist.Tracks), nil
+}
+
+// ReadJSPF reads the tracks of a JSPF playlist.
+func ReadJSPF(r io.Reader) ([]Entry, error) {
+	var document jspfDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSPF playlist: %w", err)
+	}
+
+	tracks := make([]xspfTrack, 0, len(document.Playlist.Tracks))
+	for _, track := range document.Playlist.Tracks {
+		track.xspfTrack.Locations = track.Locations
+		track.xspfTrack.Identifiers = track.Identifiers
+		tracks = append(tracks, track.xspfTrack)
+	}
+	return xspfEntries(tracks), nil
+}
+
+// xspfEntries turns XSPF tracks into entries. Spotify and ISRC identifiers make an entry exact;
+// without a title the location is parsed like a playlist path.
+func xspfEntries(tracks []xspfTrack) []Entry {
+	var entries []Entry
+	for _, track := range tracks {
+		entry := Entry{
+			Kind:     FreeText,
+			Title:    strings.TrimSpace(track.Title),
+			Artist:   strings.TrimSpace(track.Creator),
+			Album:    strings.TrimSpace(track.Album),
+			Duration: time.Duration(track.Duration) * time.Millisecond,
+		}
+
+		for _, ref := range append(append([]string{}, track.Identifiers...), track.Locations...) {
+			classified := ClassifyLine(ref)
+			if classified.Kind == SpotifyTrack {
+				entry.Kind, entry.SpotifyID = SpotifyTrack, classified.SpotifyID
+				break
+			}
+			if classified.Kind == ISRC && entry.Kind == FreeText {
+				entry.Kind, entry.ISRC = ISRC, classified.ISRC
+			}
+		}
+
+		if entry.Title == "" && len(track.Locations) > 0 {
+			if fromPath, ok := entryFromPath(track.Locations[0]); ok {
+				entry.Title = fromPath.Title
+				if entry.Artist == "" {
+					entry.Artist = fromPath.Artist
+				}
+			}
+		}
+
+		if entry.Kind == FreeText && entry.Title == "" {
+			continue
+		}
+		entry.Raw = entry.Title
+		if entry.Artist != "" {
+			entry.Raw = entry.Artist + " - " + entry.Title
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteXSPF writes the entries as an XSPF playlist. Spotify tracks get their URL as location
+// and their URI and ISRC as identifiers.
+func WriteXSPF(w io.Writer, title string, entries []Entry) error {
+	playlist := xspfPlaylist{
+		Version: "1",
+		Xmlns:   xspfNamespace,
+		Title:   title,
+		Tracks:  exportTracks(entries),
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(playlist); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJSPF writes the entries as a JSPF playlist.
+func WriteJSPF(w io.Writer, title string, entries []Entry) error {
+	var document struct {
+		Playlist struct {
+			Title  string      `json:"title,omitempty"`
+			Tracks []xspfTrack `json:"track"`
+		} `json:"playlist"`
+	}
+	document.Playlist.Title = title
+	document.Playlist.Tracks = exportTracks(entries)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func exportTracks(entries []Entry) []xspfTrack {
+	tracks := make([]xspfTrack, 0, len(entries))
+	for _, entry := range entries {
+		track := xspfTrack{
+			Title:    entry.Title,
+			Creator:  entry.Artist,
+			Album:    entry.Album,
+			Duration: entry.Duration.Milliseconds(),
+		}
+		if entry.SpotifyID != "" {
+			track.Locations = []string{entry.SpotifyURL()}
+			track.Identifiers = append(track.Identifiers, "spotify:track:"+entry.SpotifyID)
+		}
+		if entry.ISRC != "" {
+			track.Identifiers = append(track.Identifiers, "isrc:"+entry.ISRC)
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
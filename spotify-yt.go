@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"yt-spotify/config"
+	"yt-spotify/spotify"
+	"yt-spotify/youtube"
+)
+
+// SpotifyToYouTube copies a Spotify playlist, given by name, ID or link, into a YouTube playlist.
+// The YouTube playlist is called youtubeName, or like the Spotify playlist when that is empty,
+// and is reused when the signed in user already has one with that title.
+func SpotifyToYouTube(ctx context.Context, nameOrID, youtubeName string) {
+	appCtx := config.GetAppContext()
+	if nameOrID == "" {
+		fmt.Print("Enter Spotify playlist name, ID or link: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		nameOrID = strings.TrimSpace(line)
+	}
+
+	spotifyClient := authenticateSpotify(ctx, appCtx)
+
+	youtubeService := authenticateYouTube(ctx, appCtx)
+
+	playlist, err := spotify.FindPlaylist(ctx, spotifyClient, nameOrID)
+	if err != nil {
+		log.Fatalf("Unable to find Spotify playlist: %v", err)
+	}
+
+	tracks, err := spotify.FetchPlaylistTracks(ctx, spotifyClient, playlist.ID)
+	if err != nil {
+		log.Fatalf("Unable to fetch tracks of '%s': %v", playlist.Name, err)
+	}
+
+	if youtubeName == "" {
+		youtubeName = playlist.Name
+	}
+	youtubePlaylistID, err := youtube.CheckOrCreatePlaylist(ctx, youtubeService, youtubeName)
+	if err != nil {
+		log.Fatalf("Unable to find or create YouTube playlist: %v", err)
+	}
+
+	existing, err := youtube.PlaylistVideoIDs(ctx, youtubeService, youtubePlaylistID)
+	if err != nil {
+		log.Fatalf("Unable to load YouTube playlist items: %v", err)
+	}
+
+	added := 0
+	for _, track := range tracks {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, stopping.")
+			break
+		}
+		artist := ""
+		if len(track.Artists) > 0 {
+			artist = track.Artists[0]
+		}
+
+		videoID, err := youtube.SearchVideo(ctx, youtubeService, track.Name, artist)
+		if youtube.IsQuotaExceeded(err) {
+			log.Printf("YouTube API quota exceeded, stopping. Run again after the quota resets to add the rest.")
+			break
+		}
+		if err != nil {
+			log.Printf("Unable to find '%s' by '%s' on YouTube: %v", track.Name, artist, err)
+			continue
+		}
+		if existing[videoID] {
+			fmt.Printf("🟢 '%s' by '%s' already exists in YouTube playlist, skipping addition.\n", track.Name, artist)
+			continue
+		}
+
+		if err := youtube.AddVideoToPlaylist(ctx, youtubeService, youtubePlaylistID, videoID); err != nil {
+			if youtube.IsQuotaExceeded(err) {
+				log.Printf("YouTube API quota exceeded, stopping. Run again after the quota resets to add the rest.")
+				break
+			}
+			log.Printf("Unable to add '%s' by '%s': %v", track.Name, artist, err)
+			continue
+		}
+		existing[videoID] = true
+		added++
+		fmt.Printf("Added '%s' by '%s' (https://www.youtube.com/watch?v=%s)\n", track.Name, artist, videoID)
+	}
+
+	fmt.Printf("Added %d of %d tracks to YouTube playlist '%s'\n", added, len(tracks), youtubeName)
+}
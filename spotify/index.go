@@ -0,0 +1,76 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// PlaylistIndex is an in-memory set of the track IDs contained in a playlist.
+// It is loaded once per import and updated as tracks are added, so membership
+// checks do not cost a request each.
+type PlaylistIndex struct {
+	playlistID string
+	mu         sync.Mutex
+	tracks     map[string]struct{}
+}
+
+// LoadPlaylistIndex fetches every track of the playlist and builds its index.
+func LoadPlaylistIndex(ctx context.Context, client *http.Client, playlistID string) (*PlaylistIndex, error) {
+	index := &PlaylistIndex{
+		playlistID: playlistID,
+		tracks:     make(map[string]struct{}),
+	}
+
+	// Only ask for the track IDs and the pagination link
+	next := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?limit=100&fields=next,items(track(id))", playlistID)
+	for next != "" {
+		var page struct {
+			Items []struct {
+				Track *struct {
+					ID string `json:"id"`
+				} `json:"track"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+		if err := getJSON(ctx, client, next, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			// Removed and local tracks come back without an ID
+			if item.Track != nil && item.Track.ID != "" {
+				index.tracks[item.Track.ID] = struct{}{}
+			}
+		}
+		next = page.Next
+	}
+
+	return index, nil
+}
+
+// PlaylistID returns the ID of the indexed playlist.
+func (idx *PlaylistIndex) PlaylistID() string {
+	return idx.playlistID
+}
+
+// Contains reports whether the track is already in the playlist.
+func (idx *PlaylistIndex) Contains(trackID string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	_, ok := idx.tracks[trackID]
+	return ok
+}
+
+// TryAdd records the track and reports whether it was new. It checks and records in one step,
+// so goroutines sharing the index cannot both claim the same track.
+func (idx *PlaylistIndex) TryAdd(trackID string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.tracks[trackID]; ok {
+		return false
+	}
+	idx.tracks[trackID] = struct{}{}
+	return true
+}
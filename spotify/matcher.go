@@ -0,0 +1,337 @@
+package spotify
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultMatchThreshold is the minimum score a candidate needs to be accepted.
+const DefaultMatchThreshold = 0.7
+
+// DefaultDurationTolerance is how far a candidate's length may be from the source while still
+// counting as the same length.
+const DefaultDurationTolerance = 30 * time.Second
+
+// Weights of the individual signals in a candidate's score.
+const (
+	titleWeight  = 0.55
+	artistWeight = 0.30
+
+	albumBonus = 0.05
+)
+
+// durationWeight is how much the length of a candidate adds when ranking candidates. It is not
+// part of the score, so it never decides whether a candidate is accepted.
+const durationWeight = 0.15
+
+// TrackQuery describes the track we are looking for.
+type TrackQuery struct {
+	Title  string
+	Artist string
+	// Album narrows the search when known.
+	Album string
+	// Duration is the expected length of the track, zero when unknown.
+	Duration time.Duration
+}
+
+// Candidate is a Spotify track considered by the matcher.
+type Candidate struct {
+	ID       string
+	Name     string
+	Artists  []string
+	Album    string
+	Duration time.Duration
+}
+
+// Match is a scored candidate together with the reasons behind its score.
+type Match struct {
+	Candidate Candidate
+	Score     float64
+	Reasons   []string
+}
+
+// NoMatchError is returned when no candidate reaches the matcher's threshold.
+type NoMatchError struct {
+	Query     TrackQuery
+	Threshold float64
+	// Best is the highest scoring candidate, nil when the search returned nothing.
+	Best *Match
+}
+
+func (e *NoMatchError) Error() string {
+	if e.Best == nil {
+		return fmt.Sprintf("no tracks found for '%s' by '%s'", e.Query.Title, e.Query.Artist)
+	}
+	return fmt.Sprintf("best candidate '%s' by '%s' scored %.2f, below threshold %.2f (%s)",
+		e.Best.Candidate.Name, strings.Join(e.Best.Candidate.Artists, ", "), e.Best.Score, e.Threshold, strings.Join(e.Best.Reasons, ", "))
+}
+
+// Matcher scores search results against a query and picks the best one.
+type Matcher struct {
+	Threshold float64
+	// DurationTolerance is the length difference up to which candidates count as the same
+	// length. Candidates closer to the source length are preferred, which favors the album
+	// version over extended mixes and teasers when the source length is known.
+	DurationTolerance time.Duration
+}
+
+// NewMatcher returns a matcher rejecting candidates scoring below threshold and preferring
+// those within durationTolerance of the expected length. A threshold of zero selects
+// DefaultMatchThreshold and a tolerance of zero DefaultDurationTolerance.
+func NewMatcher(threshold float64, durationTolerance time.Duration) Matcher {
+	if threshold <= 0 {
+		threshold = DefaultMatchThreshold
+	}
+	if durationTolerance <= 0 {
+		durationTolerance = DefaultDurationTolerance
+	}
+	return Matcher{Threshold: threshold, DurationTolerance: durationTolerance}
+}
+
+// variantPenalties lowers the score of alternative versions the query did not ask for.
+var variantPenalties = []struct {
+	name    string
+	pattern *regexp.Regexp
+	penalty float64
+}{
+	{"karaoke", regexp.MustCompile(`(?i)\bkaraoke\b`), 0.5},
+	{"tribute", regexp.MustCompile(`(?i)\btribute\b`), 0.4},
+	{"cover", regexp.MustCompile(`(?i)\b(cover|covered by|in the style of|originally performed)\b`), 0.3},
+	{"instrumental", regexp.MustCompile(`(?i)\binstrumental\b`), 0.3},
+	{"live", regexp.MustCompile(`(?i)\blive\b`), 0.2},
+}
+
+// Best scores every candidate and returns the highest ranked one reaching the threshold, or a
+// *NoMatchError explaining why nothing was good enough. Candidates are ranked by their score
+// plus a bonus for being close to the source length.
+func (m Matcher) Best(query TrackQuery, candidates []Candidate) (*Match, error) {
+	if len(candidates) == 0 {
+		return nil, &NoMatchError{Query: query, Threshold: m.Threshold}
+	}
+
+	type ranked struct {
+		match Match
+		rank  float64
+	}
+	matches := make([]ranked, 0, len(candidates))
+	for _, candidate := range candidates {
+		match := m.Score(query, candidate)
+		matches = append(matches, ranked{match, match.Score + durationWeight*m.durationScore(query.Duration, candidate.Duration)})
+	}
+	// Stable sort keeps Spotify's relevance order between equal ranks
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank > matches[j].rank
+	})
+
+	for _, candidate := range matches {
+		if candidate.match.Score >= m.Threshold {
+			return &candidate.match, nil
+		}
+	}
+	best := matches[0].match
+	for _, candidate := range matches[1:] {
+		if candidate.match.Score > best.Score {
+			best = candidate.match
+		}
+	}
+	return nil, &NoMatchError{Query: query, Threshold: m.Threshold, Best: &best}
+}
+
+// Score rates how well a candidate matches the query on a scale from 0 to 1. The length of the
+// candidate is left out, as music videos often run well past the track with an intro or outro;
+// Best only uses it to rank candidates.
+func (m Matcher) Score(query TrackQuery, candidate Candidate) Match {
+	var reasons []string
+	total, weights := 0.0, 0.0
+
+	title := JaroWinkler(normalizeTitle(query.Title), normalizeTitle(candidate.Name))
+	total += title * titleWeight
+	weights += titleWeight
+	reasons = append(reasons, fmt.Sprintf("title %.2f", title))
+
+	if query.Artist != "" {
+		artist := artistOverlap(query, candidate.Artists)
+		total += artist * artistWeight
+		weights += artistWeight
+		reasons = append(reasons, fmt.Sprintf("artist %.2f", artist))
+	}
+
+	score := total / weights
+
+	if query.Duration > 0 && candidate.Duration > 0 {
+		delta := (query.Duration - candidate.Duration).Abs()
+		reasons = append(reasons, fmt.Sprintf("duration off by %s", delta.Round(time.Second)))
+		if m.DurationTolerance > 0 && delta > m.DurationTolerance {
+			reasons = append(reasons, "duration outside tolerance")
+		}
+	}
+
+	// Reward the requested album, which tells the original release from compilations
+	if query.Album != "" && candidate.Album != "" &&
+		JaroWinkler(normalizeTitle(query.Album), normalizeTitle(candidate.Album)) >= 0.9 {
+		score += albumBonus
+		reasons = append(reasons, "album match")
+	}
+
+	// Penalize versions the query did not ask for
+	requested := query.Title + " " + query.Artist
+	offered := candidate.Name + " " + candidate.Album + " " + strings.Join(candidate.Artists, " ")
+	for _, variant := range variantPenalties {
+		if variant.pattern.MatchString(offered) && !variant.pattern.MatchString(requested) {
+			score -= variant.penalty
+			reasons = append(reasons, variant.name+" version")
+		}
+	}
+
+	return Match{
+		Candidate: candidate,
+		Score:     math.Max(0, math.Min(1, score)),
+		Reasons:   reasons,
+	}
+}
+
+// durationScore is 1 for the source length, 0.5 at the tolerance and approaches 0 further off.
+// It is 0 when either length is unknown.
+func (m Matcher) durationScore(expected, actual time.Duration) float64 {
+	if expected <= 0 || actual <= 0 {
+		return 0
+	}
+	tolerance := m.DurationTolerance
+	if tolerance <= 0 {
+		tolerance = DefaultDurationTolerance
+	}
+	delta := (expected - actual).Abs()
+	return float64(tolerance) / float64(tolerance+delta)
+}
+
+// artistSeparator splits credits such as "A feat. B & C" into individual artists.
+var artistSeparator = regexp.MustCompile(`(?i)\s*(,|&|\+|/|\bfeat\.?|\bft\.?|\bfeaturing\b|\bx\b|\bwith\b|\band\b)\s*`)
+
+// artistOverlap averages, over every artist in the query, the best similarity with any of
+// the candidate's artists. A candidate artist named verbatim in the query counts as a full match,
+// which covers video titles like "Artist - Title" uploaded by unrelated channels.
+func artistOverlap(query TrackQuery, candidateArtists []string) float64 {
+	if len(candidateArtists) == 0 {
+		return 0
+	}
+
+	haystack := " " + normalize(query.Artist+" "+query.Title) + " "
+	for _, artist := range candidateArtists {
+		if name := normalize(artist); name != "" && strings.Contains(haystack, " "+name+" ") {
+			return 1
+		}
+	}
+
+	var queryArtists []string
+	for _, artist := range artistSeparator.Split(query.Artist, -1) {
+		if name := normalize(artist); name != "" {
+			queryArtists = append(queryArtists, name)
+		}
+	}
+	if len(queryArtists) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, queryArtist := range queryArtists {
+		best := 0.0
+		for _, artist := range candidateArtists {
+			best = math.Max(best, JaroWinkler(queryArtist, normalize(artist)))
+		}
+		total += best
+	}
+	return total / float64(len(queryArtists))
+}
+
+// bracketed matches decorations such as "(Official Video)", "[HD]" or "(feat. X)".
+var bracketed = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]|\{[^}]*\}`)
+
+// titleSuffix matches Spotify style version suffixes such as " - Remastered 2011".
+var titleSuffix = regexp.MustCompile(`(?i)\s+-\s+.*\b(remaster(ed)?|version|edit|mix|mono|stereo|live|acoustic)\b.*$`)
+
+// normalizeTitle strips bracketed decorations and version suffixes before normalizing.
+func normalizeTitle(title string) string {
+	stripped := titleSuffix.ReplaceAllString(bracketed.ReplaceAllString(title, " "), "")
+	if normalized := normalize(stripped); normalized != "" {
+		return normalized
+	}
+	return normalize(title)
+}
+
+// normalize lowercases s, replaces punctuation with spaces and collapses whitespace.
+func normalize(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "&", " and ")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		if r == '\'' || r == '’' {
+			return -1
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of a and b, between 0 and 1.
+func JaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 && len(s2) == 0 {
+		return 1
+	}
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		start, end := max(0, i-window), min(len(s2), i+window+1)
+		for j := start; j < end; j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	// Boost strings sharing a common prefix of up to four characters
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
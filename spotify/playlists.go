@@ -0,0 +1,200 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Playlist is a playlist summary as returned by the Spotify playlist listing endpoints.
+type Playlist struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Owner struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"owner"`
+	Tracks struct {
+		Total int `json:"total"`
+	} `json:"tracks"`
+	SnapshotID string `json:"snapshot_id"`
+}
+
+// PlaylistIterator pages through the current user's playlists by following the `next` links.
+type PlaylistIterator struct {
+	ctx     context.Context
+	client  *http.Client
+	next    string
+	page    []Playlist
+	current Playlist
+	err     error
+}
+
+// NewPlaylistIterator returns an iterator over every playlist of the current user. ctx aborts
+// the page requests.
+func NewPlaylistIterator(ctx context.Context, client *http.Client) *PlaylistIterator {
+	return &PlaylistIterator{
+		ctx:    ctx,
+		client: client,
+		next:   "https://api.spotify.com/v1/me/playlists?limit=50",
+	}
+}
+
+// Next advances to the next playlist, fetching the following page when needed.
+// It returns false when there are no more playlists or an error occurred.
+func (it *PlaylistIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.next == "" {
+			return false
+		}
+		var page struct {
+			Items []Playlist `json:"items"`
+			Next  string     `json:"next"`
+		}
+		if err := getJSON(it.ctx, it.client, it.next, &page); err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page.Items
+		it.next = page.Next
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// Playlist returns the playlist the iterator currently points at.
+func (it *PlaylistIterator) Playlist() Playlist {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *PlaylistIterator) Err() error {
+	return it.err
+}
+
+// getJSON performs a GET request and decodes the JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("spotify request %s failed: %s: %s", url, resp.Status, body)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Track is a track of a playlist with the metadata needed to export it.
+type Track struct {
+	ID       string
+	Name     string
+	Artists  []string
+	Album    string
+	Duration time.Duration
+	ISRC     string
+}
+
+// URL returns the open.spotify.com link of the track.
+func (t Track) URL() string {
+	return fmt.Sprintf("https://open.spotify.com/track/%s", t.ID)
+}
+
+// playlistIDPattern matches bare playlist IDs, open.spotify.com playlist links and spotify:playlist: URIs.
+var playlistIDPattern = regexp.MustCompile(`^(?:spotify:playlist:|(?:https?://)?open\.spotify\.com/(?:intl-[a-z-]+/)?playlist/)?([0-9A-Za-z]{22})(?:[/?#].*)?$`)
+
+// FindPlaylist resolves a playlist by ID, link or URI, or else by the name of one of the current user's playlists.
+func FindPlaylist(ctx context.Context, client *http.Client, nameOrID string) (Playlist, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+
+	if matches := playlistIDPattern.FindStringSubmatch(nameOrID); matches != nil {
+		var playlist Playlist
+		playlistURL := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s?fields=id,name,owner(id,display_name),tracks(total),snapshot_id", matches[1])
+		err := getJSON(ctx, client, playlistURL, &playlist)
+		if err == nil {
+			return playlist, nil
+		}
+		// A 22 character name is still possible, so fall through to the name lookup
+		fmt.Printf("No playlist with ID %s (%v), looking it up by name\n", matches[1], err)
+	}
+
+	it := NewPlaylistIterator(ctx, client)
+	for it.Next() {
+		if playlist := it.Playlist(); playlist.Name == nameOrID {
+			return playlist, nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return Playlist{}, err
+	}
+	return Playlist{}, fmt.Errorf("playlist '%s' not found", nameOrID)
+}
+
+// FetchPlaylistTracks returns every track of a playlist in playlist order, skipping
+// episodes and local files.
+func FetchPlaylistTracks(ctx context.Context, client *http.Client, playlistID string) ([]Track, error) {
+	var tracks []Track
+
+	next := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?limit=100&fields=next,items(track(id,type,name,duration_ms,artists(name),album(name),external_ids(isrc)))", playlistID)
+	for next != "" {
+		var page struct {
+			Items []struct {
+				Track *struct {
+					ID         string `json:"id"`
+					Type       string `json:"type"`
+					Name       string `json:"name"`
+					DurationMs int    `json:"duration_ms"`
+					Artists    []struct {
+						Name string `json:"name"`
+					} `json:"artists"`
+					Album struct {
+						Name string `json:"name"`
+					} `json:"album"`
+					ExternalIDs struct {
+						ISRC string `json:"isrc"`
+					} `json:"external_ids"`
+				} `json:"track"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+		if err := getJSON(ctx, client, next, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			if item.Track == nil || item.Track.ID == "" || item.Track.Type != "track" {
+				continue
+			}
+			artists := make([]string, 0, len(item.Track.Artists))
+			for _, artist := range item.Track.Artists {
+				artists = append(artists, artist.Name)
+			}
+			tracks = append(tracks, Track{
+				ID:       item.Track.ID,
+				Name:     item.Track.Name,
+				Artists:  artists,
+				Album:    item.Track.Album.Name,
+				Duration: time.Duration(item.Track.DurationMs) * time.Millisecond,
+				ISRC:     item.Track.ExternalIDs.ISRC,
+			})
+		}
+		next = page.Next
+	}
+
+	return tracks, nil
+}
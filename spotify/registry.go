@@ -0,0 +1,62 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// PlaylistRegistry resolves playlist names to IDs for concurrent imports. The check-then-create
+// step runs for one name at a time, and resolved IDs and loaded indexes are shared, so goroutines
+// importing into the same playlist neither create duplicates nor add the same track twice.
+type PlaylistRegistry struct {
+	client *http.Client
+
+	// mu serializes lookups and creation; it is held across the Spotify requests on purpose
+	mu      sync.Mutex
+	ids     map[string]string
+	indexes map[string]*PlaylistIndex
+}
+
+// NewPlaylistRegistry returns an empty registry using client for its requests.
+func NewPlaylistRegistry(client *http.Client) *PlaylistRegistry {
+	return &PlaylistRegistry{
+		client:  client,
+		ids:     make(map[string]string),
+		indexes: make(map[string]*PlaylistIndex),
+	}
+}
+
+// PlaylistID returns the ID of the playlist called name like CheckOrCreatePlaylist, creating it
+// with description at most once. Every caller asking for the same name gets the same ID.
+// Failures are not remembered, so a later call tries again.
+func (r *PlaylistRegistry) PlaylistID(ctx context.Context, name, description string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, found := r.ids[name]; found {
+		return id, nil
+	}
+	id, err := CheckOrCreatePlaylist(ctx, r.client, name, description)
+	if err != nil {
+		return "", err
+	}
+	r.ids[name] = id
+	return id, nil
+}
+
+// Index returns the shared index of a playlist, loading it on first use.
+func (r *PlaylistRegistry) Index(ctx context.Context, playlistID string) (*PlaylistIndex, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if index, found := r.indexes[playlistID]; found {
+		return index, nil
+	}
+	index, err := LoadPlaylistIndex(ctx, r.client, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	r.indexes[playlistID] = index
+	return index, nil
+}
@@ -7,200 +7,143 @@ import (
 	"golang.org/x/oauth2"
 	"net/http"
 	"net/url"
-	"os"
-	"os/exec"
 	"regexp"
-	"runtime"
 	"strings"
+	"time"
+	"yt-spotify/auth"
 )
 
-var authCodeChannel = make(chan string) // Channel to receive the authorization code
-
-// ExtractPort extracts the port from a localhost redirect URI
-func extractPort(redirectURI string) string {
-	parsedURL, err := url.Parse(redirectURI)
-	if err != nil {
-		fmt.Println("Error parsing redirect URI:", err)
-		return "8087" // Default fallback port
-	}
-
-	port := parsedURL.Port()
-	if port == "" {
-		return "8087" // Default port if not specified
-	}
-	return port
-}
-
-// Start a local server to capture the auth code from the redirect
-func startAuthServer(port string) {
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query()
-		code := query.Get("code")
-		if code != "" {
-			fmt.Fprintln(w, "Authorization successful! You can close this window.")
-			authCodeChannel <- code // Send the code to the main function
-		} else {
-			fmt.Fprintln(w, "Authorization failed. No code received.")
-		}
-	})
-
-	address := fmt.Sprintf(":%s", port)
-	fmt.Println("Starting local server on http://localhost:" + address)
-	go func() {
-		if err := http.ListenAndServe(address, nil); err != nil {
-			fmt.Println("Error starting server:", err)
-		}
-	}()
-}
-
-// Open the authorization URL in the user's default browser
-func openBrowser(url string) error {
-	fmt.Println("Opening URL in browser:", url) // Debugging: Print the URL
-
-	var cmd string
-	var args []string
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = "powershell.exe"
-		args = []string{"Start-Process", url} // PowerShell on Windows
-	case "darwin":
-		cmd = "open"
-		args = []string{url}
-	case "linux":
-		// Detect if running inside WSL and use `wslview`
-		if isWSL() {
-			cmd = "wsl-open"
-		} else {
-			cmd = "xdg-open"
-		}
-		args = []string{url}
-	}
-
-	err := exec.Command(cmd, args...).Start()
-	if err != nil {
-		fmt.Println("Failed to open browser. Please manually visit:", url)
-	}
-	return err
+// tokenCacheFile is the name of the cached Spotify token inside the user config directory.
+const tokenCacheFile = "spotify_token.json"
+
+// AuthOptions configures how Authenticate obtains a Spotify token.
+type AuthOptions struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	// Headless prints the authorization URL instead of opening a browser and reads the
+	// redirect URL or code from stdin, for servers and containers.
+	Headless bool
 }
 
-// Detect if running inside WSL
-func isWSL() bool {
-	_, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop")
-	return err == nil
-
-}
-
-// Authenticate authenticates with Spotify and returns an HTTP client.
-func Authenticate(clientID, clientSecret, redirectURI string) (*http.Client, error) {
+// OAuthConfig returns the OAuth2 configuration for the Spotify accounts service. Without a
+// client secret it is set up for the PKCE flow.
+func OAuthConfig(opts AuthOptions) *oauth2.Config {
 	conf := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  redirectURI,
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		RedirectURL:  opts.RedirectURI,
 		Scopes:       []string{"playlist-modify-public", "playlist-modify-private", "playlist-read-private"},
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  "https://accounts.spotify.com/authorize",
 			TokenURL: "https://accounts.spotify.com/api/token",
 		},
 	}
-	var isLocal bool = false
-	// if its local run local server
-	if strings.HasPrefix(redirectURI, "http://localhost") {
-		port := extractPort(redirectURI)
-		startAuthServer(port)
-		isLocal = true
+	if auth.UsePKCE(conf) {
+		// Public clients have to send their client_id in the body of token and refresh requests
+		conf.Endpoint.AuthStyle = oauth2.AuthStyleInParams
 	}
+	return conf
+}
 
-	// Redirect user to Spotify authorization page
-	authURL := conf.AuthCodeURL("state", oauth2.AccessTypeOffline)
-
-	fmt.Println("Opening browser for Spotify authentication...")
-	if err := openBrowser(authURL); err != nil {
-		fmt.Println("Failed to open browser. Please manually visit the URL below and enter the code after authorization:")
-		fmt.Println(authURL)
-	}
-	var code string
-	if isLocal {
-		// Wait for the authorization code to be received from the local server
-		code = <-authCodeChannel
-	} else {
+// Authenticate authenticates with Spotify and returns an HTTP client.
+// A cached token is reused and refreshed when possible; the browser flow only runs when
+// there is no usable token. Without a client secret the Authorization Code with PKCE flow is used.
+// ctx aborts the sign-in.
+func Authenticate(ctx context.Context, opts AuthOptions) (*http.Client, error) {
+	conf := OAuthConfig(opts)
+
+	// Route the token exchange and every API call through the shared rate limited transport
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: NewRateLimitedTransport(http.DefaultTransport, defaultRequestsPerSecond, defaultBurst),
+	})
 
-		fmt.Printf("Visit the URL for the auth dialog: %v\n", authURL)
-		// After authorization, Spotify will redirect to the redirect URI with a code
-		fmt.Print("Enter the code from the redirect URL: ")
-		fmt.Scanln(&code)
+	cache, err := auth.NewTokenCache(tokenCacheFile)
+	if err != nil {
+		return nil, err
 	}
 
-	// Exchange the code for a token
-	token, err := conf.Exchange(context.Background(), code)
+	tokenSource, err := auth.CachedTokenSource(ctx, conf, cache, "Spotify", opts.Headless)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create an HTTP client using the token
-	client := conf.Client(context.Background(), token)
-
-	return client, nil
+	// Create an HTTP client using the token; requests carry their own context
+	return oauth2.NewClient(context.WithoutCancel(ctx), tokenSource), nil
 }
 
-func CheckOrCreatePlaylist(client *http.Client, name string) (string, error) {
-	userId, err := getSpotifyUserID(client)
+// ImportToken stores a token JSON file produced on another machine as the cached Spotify token.
+func ImportToken(path string) error {
+	cache, err := auth.NewTokenCache(tokenCacheFile)
 	if err != nil {
-		return "", err
+		return err
 	}
-	fmt.Println("User ID:", userId)
-	// Check existing playlists
-	req, err := http.NewRequest("GET", "https://api.spotify.com/v1/me/playlists", nil)
-	if err != nil {
-		return "", err
+	if err := cache.Import(path); err != nil {
+		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	fmt.Println("Imported Spotify token to", cache.Path())
+	return nil
+}
 
-	resp, err := client.Do(req)
+// Logout deletes the cached Spotify token so the next run authenticates again.
+func Logout() error {
+	cache, err := auth.NewTokenCache(tokenCacheFile)
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer resp.Body.Close()
-
-	var playlists struct {
-		Items []struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-		} `json:"items"`
+	if err := cache.Delete(); err != nil {
+		return err
 	}
+	fmt.Println("Removed cached Spotify token", cache.Path())
+	return nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&playlists); err != nil {
-		return "", err
-	}
+// defaultPlaylistDescription is the description of created playlists when none is given.
+const defaultPlaylistDescription = "Playlist imported"
 
-	// Print playlists
-	fmt.Println("Existing Spotify Playlists:")
-	for _, playlist := range playlists.Items {
-		fmt.Printf("- %s (ID: %s)\n", playlist.Name, playlist.ID)
+// CheckOrCreatePlaylist returns the ID of the current user's playlist called name,
+// creating it with the given description when the user does not own one yet.
+// An empty description uses a generic one.
+func CheckOrCreatePlaylist(ctx context.Context, client *http.Client, name, description string) (string, error) {
+	userID, err := getSpotifyUserID(ctx, client)
+	if err != nil {
+		return "", err
 	}
+	fmt.Println("User ID:", userID)
 
-	// Check if the playlist already exists
-	for _, playlist := range playlists.Items {
-		if playlist.Name == name {
-			fmt.Println("Playlist already exists:", playlist.Name)
+	// Walk every page of the user's playlists; only playlists they own can be reused
+	it := NewPlaylistIterator(ctx, client)
+	for it.Next() {
+		playlist := it.Playlist()
+		if playlist.Owner.ID == userID && playlist.Name == name {
+			fmt.Printf("Playlist already exists: %s (ID: %s, %d tracks)\n", playlist.Name, playlist.ID, playlist.Tracks.Total)
 			return playlist.ID, nil
 		}
 	}
+	if err := it.Err(); err != nil {
+		return "", err
+	}
 
 	// If playlist does not exist, create it
-	return CreatePlaylist(client, name)
+	return createPlaylist(ctx, client, userID, name, description)
 }
 
 // CreatePlaylist creates a new Spotify playlist and returns its ID.
-func CreatePlaylist(client *http.Client, name string) (string, error) {
-	userID, err := getSpotifyUserID(client)
+func CreatePlaylist(ctx context.Context, client *http.Client, name, description string) (string, error) {
+	userID, err := getSpotifyUserID(ctx, client)
 	if err != nil {
 		return "", err
 	}
+	return createPlaylist(ctx, client, userID, name, description)
+}
 
+func createPlaylist(ctx context.Context, client *http.Client, userID, name, description string) (string, error) {
+	if description == "" {
+		description = defaultPlaylistDescription
+	}
 	reqBody := map[string]interface{}{
 		"name":        name,
-		"description": "Playlist imported",
+		"description": description,
 		"public":      true,
 	}
 
@@ -209,7 +152,7 @@ func CreatePlaylist(client *http.Client, name string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.spotify.com/v1/users/%s/playlists", userID), strings.NewReader(string(reqBodyJSON)))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://api.spotify.com/v1/users/%s/playlists", userID), strings.NewReader(string(reqBodyJSON)))
 	if err != nil {
 		return "", err
 	}
@@ -221,195 +164,226 @@ func CreatePlaylist(client *http.Client, name string) (string, error) {
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create playlist: %s", resp.Status)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", err
 	}
 
-	return result["id"].(string), nil
+	return result.ID, nil
 }
 
-func getSpotifyUserID(client *http.Client) (string, error) {
-	req, err := http.NewRequest("GET", "https://api.spotify.com/v1/me", nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+func getSpotifyUserID(ctx context.Context, client *http.Client) (string, error) {
+	var result struct {
+		ID string `json:"id"`
 	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := getJSON(ctx, client, "https://api.spotify.com/v1/me", &result); err != nil {
 		return "", err
 	}
 
-	return result["id"].(string), nil
+	return result.ID, nil
 }
 
+// noiseWords matches keywords that only get in the way of a search. Whole words only, so titles
+// like "Stayin' Alive" or names like "Oliver Tree" are left alone.
+var noiseWords = regexp.MustCompile(`(?i)\b(official video|remastered|4k|live|audio|topic|vevo|remix)\b`)
+
 // cleanText removes unnecessary keywords from track or artist names.
 func cleanText(input string) string {
-	re := regexp.MustCompile(`(?i)(official video|remastered|4k|live|audio|topic|vevo|remix)`)
-	cleaned := re.ReplaceAllString(input, "")
+	cleaned := noiseWords.ReplaceAllString(input, "")
 	return strings.TrimSpace(cleaned)
 }
 
-// SearchTrack searches for a track on Spotify and returns its ID.
-func SearchTrack(client *http.Client, trackName, artistName string) (string, error) {
-	// Clean track and artist names
-	trackName = cleanText(trackName)
-	artistName = cleanText(artistName)
-
-	fmt.Printf("Searching for track: '%s' by artist: '%s'\n", trackName, artistName)
+// searchResult is the subset of a track object returned by the search endpoint that we use.
+type searchResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+	DurationMs  int `json:"duration_ms"`
+	ExternalIDs struct {
+		ISRC string `json:"isrc"`
+	} `json:"external_ids"`
+}
 
-	// First, try an exact match with track and artist
-	query := url.QueryEscape(fmt.Sprintf("track:%s artist:%s", trackName, artistName))
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=track&limit=5", query), nil)
-	if err != nil {
-		return "", err
+// candidate converts the search result into a matcher candidate.
+func (r searchResult) candidate() Candidate {
+	artists := make([]string, 0, len(r.Artists))
+	for _, artist := range r.Artists {
+		artists = append(artists, artist.Name)
 	}
+	return Candidate{
+		ID:       r.ID,
+		Name:     r.Name,
+		Artists:  artists,
+		Album:    r.Album.Name,
+		Duration: time.Duration(r.DurationMs) * time.Millisecond,
+	}
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// searchTracks runs a track search query and returns the matching tracks.
+func searchTracks(ctx context.Context, client *http.Client, query string, limit int) ([]searchResult, error) {
+	var result struct {
+		Tracks struct {
+			Items []searchResult `json:"items"`
+		} `json:"tracks"`
 	}
-	defer resp.Body.Close()
+	searchURL := fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=track&limit=%d", url.QueryEscape(query), limit)
+	if err := getJSON(ctx, client, searchURL, &result); err != nil {
+		return nil, err
+	}
+	return result.Tracks.Items, nil
+}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+// FindTrackByISRC looks up a track by its exact ISRC code.
+func FindTrackByISRC(ctx context.Context, client *http.Client, isrc string) (*Match, error) {
+	results, err := searchTracks(ctx, client, fmt.Sprintf("isrc:%s", isrc), 10)
+	if err != nil {
+		return nil, err
 	}
 
-	// Handle Spotify's response for the first query
-	tracks := result["tracks"].(map[string]interface{})["items"].([]interface{})
-	if len(tracks) > 0 {
-		// Attempt to find the best match in the first result set
-		for _, t := range tracks {
-			track := t.(map[string]interface{})
-			trackTitle := track["name"].(string)
-			trackArtist := track["artists"].([]interface{})[0].(map[string]interface{})["name"].(string)
-
-			// Fuzzy match: Check if Spotify track matches the cleaned inputs
-			if strings.Contains(strings.ToLower(trackTitle), strings.ToLower(trackName)) &&
-				strings.Contains(strings.ToLower(trackArtist), strings.ToLower(artistName)) {
-				return track["id"].(string), nil
-			}
+	for _, result := range results {
+		if strings.EqualFold(result.ExternalIDs.ISRC, isrc) {
+			return &Match{Candidate: result.candidate(), Score: 1, Reasons: []string{"isrc"}}, nil
 		}
 	}
+	return nil, fmt.Errorf("no track found with ISRC %s", isrc)
+}
 
-	// If no match is found, try a broader search with just the track name
-	fmt.Printf("Exact match failed for '%s' by '%s'. Trying broader search...\n", trackName, artistName)
-	query = url.QueryEscape(fmt.Sprintf("track:%s", trackName))
-	req, err = http.NewRequest("GET", fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=track&limit=5", query), nil)
-	if err != nil {
-		return "", err
-	}
+// FindTrack searches Spotify for the query and returns the best scoring candidate.
+// When nothing reaches the matcher's threshold a *NoMatchError describes the closest candidate.
+func FindTrack(ctx context.Context, client *http.Client, matcher Matcher, query TrackQuery) (*Match, error) {
+	// Clean track and artist names for the search itself, the matcher sees the originals
+	trackName := cleanText(query.Title)
+	artistName := cleanText(query.Artist)
+	albumName := cleanText(query.Album)
 
-	resp, err = client.Do(req)
+	fmt.Printf("Searching for track: '%s' by artist: '%s'\n", trackName, artistName)
+
+	// First, try a fielded search with track, artist and album
+	fielded := fmt.Sprintf("track:%s", trackName)
+	if artistName != "" {
+		fielded += fmt.Sprintf(" artist:%s", artistName)
+	}
+	if albumName != "" {
+		fielded += fmt.Sprintf(" album:%s", albumName)
+	}
+	results, err := searchTracks(ctx, client, fielded, 10)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	candidates := make([]Candidate, 0, len(results))
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if !seen[result.ID] {
+			seen[result.ID] = true
+			candidates = append(candidates, result.candidate())
+		}
 	}
 
-	// Handle Spotify's response for the broader query
-	tracks = result["tracks"].(map[string]interface{})["items"].([]interface{})
-	if len(tracks) > 0 {
-		// Attempt to find the best match in the broader result set
-		for _, t := range tracks {
-			track := t.(map[string]interface{})
-			trackTitle := track["name"].(string)
+	match, err := matcher.Best(query, candidates)
+	if err == nil {
+		return match, nil
+	}
 
-			// Loose match: Check if Spotify track matches the cleaned track name
-			if strings.Contains(strings.ToLower(trackTitle), strings.ToLower(trackName)) {
-				return track["id"].(string), nil
-			}
+	// If no match is good enough, try a broader free text search
+	fmt.Printf("Exact match failed for '%s' by '%s'. Trying broader search...\n", trackName, artistName)
+	results, err = searchTracks(ctx, client, strings.TrimSpace(trackName+" "+artistName), 10)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if !seen[result.ID] {
+			seen[result.ID] = true
+			candidates = append(candidates, result.candidate())
 		}
 	}
 
-	// If no matches were found at all
-	return "", fmt.Errorf("no suitable tracks found for '%s' by '%s'", trackName, artistName)
+	return matcher.Best(query, candidates)
 }
 
-// AddTrackToPlaylist adds a track to a Spotify playlist.
-func AddTrackToPlaylist(client *http.Client, playlistID, trackID string) error {
+// maxTracksPerRequest is the number of URIs the add-items endpoint accepts at once.
+const maxTracksPerRequest = 100
 
-	exists, err := IsTrackInPlaylist(client, playlistID, trackID)
-	if err != nil {
-		return err
-	}
+// AddTracksResult is the outcome of adding one chunk of URIs to a playlist.
+type AddTracksResult struct {
+	URIs       []string
+	SnapshotID string
+	Err        error
+}
+
+// TrackURI returns the Spotify URI for a track ID.
+func TrackURI(trackID string) string {
+	return fmt.Sprintf("spotify:track:%s", trackID)
+}
+
+// AddTracksToPlaylist appends the URIs to a playlist in chunks of up to 100, keeping their order.
+// It returns one result per chunk sent and the snapshot ID of the last successful chunk.
+// Adding stops at the first failing chunk so later tracks are never placed before earlier ones.
+func AddTracksToPlaylist(ctx context.Context, client *http.Client, playlistID string, uris []string) ([]AddTracksResult, string, error) {
+	var results []AddTracksResult
+	var snapshotID string
+
+	for start := 0; start < len(uris); start += maxTracksPerRequest {
+		end := start + maxTracksPerRequest
+		if end > len(uris) {
+			end = len(uris)
+		}
+		chunk := uris[start:end]
 
-	if exists {
-		fmt.Println("🟢 Track already exists in playlist, skipping addition.")
-		return nil
+		chunkSnapshot, err := addTracksChunk(ctx, client, playlistID, chunk)
+		results = append(results, AddTracksResult{URIs: chunk, SnapshotID: chunkSnapshot, Err: err})
+		if err != nil {
+			return results, snapshotID, err
+		}
+		snapshotID = chunkSnapshot
 	}
 
+	return results, snapshotID, nil
+}
+
+func addTracksChunk(ctx context.Context, client *http.Client, playlistID string, uris []string) (string, error) {
 	reqBody := map[string]interface{}{
-		"uris": []string{fmt.Sprintf("spotify:track:%s", trackID)},
+		"uris": uris,
 	}
 
 	reqBodyJSON, err := json.Marshal(reqBody)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks", playlistID), strings.NewReader(string(reqBodyJSON)))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks", playlistID), strings.NewReader(string(reqBodyJSON)))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to add track to playlist: %s", resp.Status)
+		return "", fmt.Errorf("failed to add tracks to playlist: %s", resp.Status)
 	}
 
-	return nil
-}
-
-func IsTrackInPlaylist(client *http.Client, playlistID, trackID string) (bool, error) {
-	url := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks", playlistID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return false, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-
-	// Step 2: Parse response
 	var result struct {
-		Items []struct {
-			Track struct {
-				ID string `json:"id"`
-			} `json:"track"`
-		} `json:"items"`
+		SnapshotID string `json:"snapshot_id"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return false, err
-	}
-
-	for _, item := range result.Items {
-		if item.Track.ID == trackID {
-			return true, nil // Track exists
-		}
+		return "", err
 	}
 
-	return false, nil // Track not found
+	return result.SnapshotID, nil
 }
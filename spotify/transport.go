@@ -0,0 +1,233 @@
+package spotify
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRequestsPerSecond and defaultBurst keep the app well below Spotify's rolling rate limit.
+	defaultRequestsPerSecond = 8
+	defaultBurst             = 8
+	defaultMaxRetries        = 5
+
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// RateLimitedTransport is an http.RoundTripper that throttles requests with a token bucket,
+// honors Retry-After on 429 responses and retries transient failures with jittered backoff.
+// A single transport is shared by every goroutine using the client, so the limit is global.
+type RateLimitedTransport struct {
+	base       http.RoundTripper
+	limiter    *tokenBucket
+	maxRetries int
+}
+
+// NewRateLimitedTransport wraps base, allowing requestsPerSecond requests with bursts of up to burst.
+func NewRateLimitedTransport(base http.RoundTripper, requestsPerSecond float64, burst int) *RateLimitedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RateLimitedTransport{
+		base:       base,
+		limiter:    newTokenBucket(requestsPerSecond, burst),
+		maxRetries: defaultMaxRetries,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			// The previous attempt consumed the body, rewind it
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		delay, retry := t.retryDelay(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Printf("Spotify %s %s failed: %v, retrying in %s (attempt %d/%d)", req.Method, req.URL.Path, err, delay.Round(time.Millisecond), attempt+1, t.maxRetries)
+		} else {
+			log.Printf("Spotify %s %s returned %s, retrying in %s (attempt %d/%d)", req.Method, req.URL.Path, resp.Status, delay.Round(time.Millisecond), attempt+1, t.maxRetries)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay decides whether a request should be retried and how long to wait first.
+func (t *RateLimitedTransport) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= t.maxRetries {
+		return 0, false
+	}
+	// A body that cannot be replayed cannot be retried
+	if req.Body != nil && req.GetBody == nil {
+		return 0, false
+	}
+
+	if err != nil {
+		if req.Context().Err() != nil || !isIdempotent(req.Method) {
+			return 0, false
+		}
+		return backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Spotify rejected the request before processing it, so any method is safe to resend
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		switch {
+		case !ok:
+			delay = backoff(attempt)
+		case delay > maxBackoff:
+			// Waiting that long would stall every caller, so hand the 429 back instead
+			log.Printf("Spotify %s %s asked to wait %s before retrying, giving up", req.Method, req.URL.Path, delay)
+			return 0, false
+		default:
+			delay = min(delay+jitter(baseBackoff), maxBackoff)
+		}
+		// Hold back every other caller sharing the limiter too
+		t.limiter.pauseFor(delay)
+		return delay, true
+	case resp.StatusCode >= 500 && isIdempotent(req.Method):
+		return backoff(attempt), true
+	}
+
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay for the attempt with up to 50% jitter.
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << uint(attempt)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	return delay/2 + jitter(delay/2)
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// tokenBucket is a minimal token bucket limiter safe for concurrent use.
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newTokenBucket(requestsPerSecond float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+
+		var delay time.Duration
+		switch {
+		case now.Before(b.pausedUntil):
+			delay = b.pausedUntil.Sub(now)
+		case b.tokens >= 1 || b.rate <= 0:
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		default:
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pauseFor stops handing out tokens for the given duration.
+func (b *tokenBucket) pauseFor(delay time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"yt-spotify/utils"
+)
+
+// Track is a song extracted from a YouTube video and, once searched, its Spotify match.
+type Track struct {
+	Title    string        `json:"title"`
+	Artist   string        `json:"artist,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	// Unsplit is the whole line the title and artist were split from on " by ", searched as a
+	// title when the split finds nothing.
+	Unsplit string `json:"unsplit,omitempty"`
+	// Searched tells whether Spotify was searched; SpotifyID is empty when nothing matched.
+	Searched  bool   `json:"searched,omitempty"`
+	SpotifyID string `json:"spotify_id,omitempty"`
+}
+
+// Video is the import progress of one YouTube video.
+type Video struct {
+	// Extracted tells whether Tracks holds the final extraction result.
+	Extracted bool    `json:"extracted,omitempty"`
+	Tracks    []Track `json:"tracks,omitempty"`
+	// AddedTo is the Spotify playlist the matches were added to, empty until they are.
+	AddedTo string `json:"added_to,omitempty"`
+}
+
+// Searched tells whether every extracted track was searched on Spotify.
+func (v Video) Searched() bool {
+	if !v.Extracted {
+		return false
+	}
+	for _, track := range v.Tracks {
+		if !track.Searched {
+			return false
+		}
+	}
+	return true
+}
+
+// document is the layout of the state file: videos by source playlist and video ID.
+type document struct {
+	Sources map[string]map[string]*Video `json:"sources"`
+}
+
+// Store keeps the progress of YouTube imports in a JSON file, so that a rerun can skip the
+// extractions, searches and additions an earlier run already did. It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu  sync.Mutex
+	doc document
+}
+
+// Open loads the store at path. A missing file, or fresh, gives an empty store that replaces
+// the file on the next Save.
+func Open(path string, fresh bool) (*Store, error) {
+	store := &Store{path: path, doc: document{Sources: make(map[string]map[string]*Video)}}
+	if fresh {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.doc); err != nil {
+		return nil, fmt.Errorf("invalid import state %s: %w", path, err)
+	}
+	if store.doc.Sources == nil {
+		store.doc.Sources = make(map[string]map[string]*Video)
+	}
+	return store, nil
+}
+
+// Path returns the location of the state file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Video returns a copy of the progress of a video, or the zero Video when there is none.
+func (s *Store) Video(source, videoID string) Video {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	video, ok := s.doc.Sources[source][videoID]
+	if !ok {
+		return Video{}
+	}
+	copied := *video
+	copied.Tracks = append([]Track(nil), video.Tracks...)
+	return copied
+}
+
+// Update changes the progress of a video in place, creating it when needed.
+func (s *Store) Update(source, videoID string, f func(*Video)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	videos, ok := s.doc.Sources[source]
+	if !ok {
+		videos = make(map[string]*Video)
+		s.doc.Sources[source] = videos
+	}
+	video, ok := videos[videoID]
+	if !ok {
+		video = &Video{}
+		videos[videoID] = video
+	}
+	f(video)
+}
+
+// Save writes the store to its file.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.doc)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFileAtomic(s.path, data, 0644)
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAddTracks records the URIs of every add-items request and fails the request numbered
+// failAt (1-based), if any.
+type fakeAddTracks struct {
+	mu     sync.Mutex
+	chunks [][]string
+	failAt int
+}
+
+func (f *fakeAddTracks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.URL.Path != "/v1/playlists/playlist1/tracks" {
+		http.NotFound(w, r)
+		return
+	}
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	f.chunks = append(f.chunks, body.URIs)
+	n := len(f.chunks)
+	f.mu.Unlock()
+
+	if n == f.failAt {
+		http.Error(w, `{"error":{"status":500}}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"snapshot_id":"snapshot%d"}`, n)
+}
+
+func trackURIs(n int) []string {
+	uris := make([]string, n)
+	for i := range uris {
+		uris[i] = spotify.TrackURI(fmt.Sprintf("track%d", i))
+	}
+	return uris
+}
+
+// Test that URIs are sent in order in chunks of at most 100
+func TestAddTracksToPlaylist_Chunks(t *testing.T) {
+	testCases := []struct {
+		uris   int
+		chunks []int
+	}{
+		{0, nil},
+		{100, []int{100}},
+		{101, []int{100, 1}},
+		{250, []int{100, 100, 50}},
+	}
+
+	for _, tc := range testCases {
+		fake := &fakeAddTracks{}
+		server := httptest.NewServer(fake)
+		target, _ := url.Parse(server.URL)
+		client := &http.Client{Transport: redirectTransport{target: target}}
+
+		uris := trackURIs(tc.uris)
+		results, snapshotID, err := spotify.AddTracksToPlaylist(context.Background(), client, "playlist1", uris)
+		server.Close()
+
+		assert.NoError(t, err, "%d URIs", tc.uris)
+		assert.Len(t, results, len(tc.chunks), "%d URIs", tc.uris)
+		var sent []string
+		for i, chunk := range fake.chunks {
+			assert.Len(t, chunk, tc.chunks[i], "%d URIs, chunk %d", tc.uris, i)
+			assert.Equal(t, chunk, results[i].URIs)
+			assert.NoError(t, results[i].Err)
+			sent = append(sent, chunk...)
+		}
+		assert.Equal(t, len(uris), len(sent))
+		if tc.uris > 0 {
+			assert.Equal(t, uris, sent, "URIs should keep their order")
+			assert.Equal(t, fmt.Sprintf("snapshot%d", len(tc.chunks)), snapshotID)
+		} else {
+			assert.Empty(t, snapshotID)
+		}
+	}
+}
+
+// Test that adding stops at a failing chunk and reports the snapshot of the last successful one
+func TestAddTracksToPlaylist_StopsAtFailure(t *testing.T) {
+	fake := &fakeAddTracks{failAt: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	client := &http.Client{Transport: redirectTransport{target: target}}
+
+	uris := trackURIs(250)
+	results, snapshotID, err := spotify.AddTracksToPlaylist(context.Background(), client, "playlist1", uris)
+
+	assert.Error(t, err)
+	assert.Len(t, fake.chunks, 2, "The chunk after the failing one should not be sent")
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, uris[:100], results[0].URIs)
+	assert.Equal(t, "snapshot1", results[0].SnapshotID)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, uris[100:200], results[1].URIs)
+	assert.Equal(t, "snapshot1", snapshotID)
+}
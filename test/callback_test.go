@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"yt-spotify/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that the callback server hands over the code when the state matches
+func TestCallbackServer_ReceivesCode(t *testing.T) {
+	server, err := auth.StartCallbackServer("http://127.0.0.1:0/callback", "expected-state")
+	assert.NoError(t, err)
+
+	go func() {
+		resp, err := http.Get("http://" + server.Addr() + "/callback?code=abc&state=expected-state")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	code, err := server.Wait(context.Background(), 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", code)
+}
+
+// Test that a mismatched state and an error parameter are both rejected
+func TestCallbackServer_RejectsBadCallbacks(t *testing.T) {
+	testCases := []struct {
+		query    string
+		errorMsg string
+	}{
+		{"code=abc&state=forged", "state mismatch"},
+		{"error=access_denied&state=expected-state", "access_denied"},
+	}
+
+	for _, tc := range testCases {
+		server, err := auth.StartCallbackServer("http://127.0.0.1:0/callback", "expected-state")
+		assert.NoError(t, err)
+
+		resp, err := http.Get("http://" + server.Addr() + "/callback?" + tc.query)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		_, err = server.Wait(context.Background(), 5*time.Second)
+		assert.ErrorContains(t, err, tc.errorMsg)
+	}
+}
+
+// Test that waiting gives up after the timeout
+func TestCallbackServer_TimesOut(t *testing.T) {
+	server, err := auth.StartCallbackServer("http://127.0.0.1:0/callback", "expected-state")
+	assert.NoError(t, err)
+
+	_, err = server.Wait(context.Background(), 50*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+// Test that waiting stops when the context is cancelled, e.g. by Ctrl-C
+func TestCallbackServer_Cancelled(t *testing.T) {
+	server, err := auth.StartCallbackServer("http://127.0.0.1:0/callback", "expected-state")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = server.Wait(ctx, 5*time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+}
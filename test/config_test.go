@@ -0,0 +1,36 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+	"yt-spotify/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that PLAYLISTS accepts bare IDs and objects with an access mode
+func TestPlaylistSourceUnmarshal(t *testing.T) {
+	var sources []config.PlaylistSource
+	err := json.Unmarshal([]byte(`["PLpublic", {"id": "PLprivate", "auth": "OAuth"}, {"id": "LL", "name": " Likes "}]`), &sources)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.PlaylistSource{
+		{ID: "PLpublic"},
+		{ID: "PLprivate", Auth: config.AuthOAuth},
+		{ID: "LL", Name: "Likes"},
+	}, sources)
+
+	err = json.Unmarshal([]byte(`[{"id": "PLx", "auth": "password"}]`), &sources)
+	assert.Error(t, err)
+}
+
+// Test the default access mode of playlists
+func TestPlaylistAuth(t *testing.T) {
+	withKey := &config.AppContext{YouTubeAPIKey: "key"}
+	assert.Equal(t, config.AuthAPIKey, withKey.PlaylistAuth(config.PlaylistSource{ID: "PLpublic"}))
+	assert.Equal(t, config.AuthOAuth, withKey.PlaylistAuth(config.PlaylistSource{ID: "PLprivate", Auth: config.AuthOAuth}))
+	assert.Equal(t, config.AuthOAuth, withKey.PlaylistAuth(config.PlaylistSource{ID: "LL", Auth: config.AuthAPIKey}))
+	assert.Equal(t, config.AuthOAuth, withKey.PlaylistAuth(config.PlaylistSource{ID: "WL"}))
+
+	withoutKey := &config.AppContext{}
+	assert.Equal(t, config.AuthOAuth, withoutKey.PlaylistAuth(config.PlaylistSource{ID: "PLpublic"}))
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+	"yt-spotify/songs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var exportedEntries = []songs.Entry{
+	{
+		Kind:      songs.SpotifyTrack,
+		Title:     "Under Pressure",
+		Artist:    "Queen, David Bowie",
+		Artists:   []string{"Queen", "David Bowie"},
+		Album:     "Hot Space",
+		SpotifyID: "2fuCquhmrzHpu5xcA1ci9x",
+		ISRC:      "GBUM71108206",
+		Duration:  248 * time.Second,
+	},
+	{
+		Kind:      songs.SpotifyTrack,
+		Title:     "Tyler, The Creator Interlude",
+		Artist:    "Tyler, The Creator",
+		Artists:   []string{"Tyler, The Creator"},
+		SpotifyID: "4u7EnebtmKWzUH433cf5Qv",
+	},
+}
+
+// Test that exported CSV carries every column and is read back by the CSV importer
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, songs.WriteCSV(&out, exportedEntries))
+	assert.Contains(t, out.String(), "Track Name,Artist Name(s),Album Name,ISRC,Duration (ms),Spotify URL\n")
+	assert.Contains(t, out.String(), `Under Pressure,"Queen, David Bowie",Hot Space,GBUM71108206,248000,https://open.spotify.com/track/2fuCquhmrzHpu5xcA1ci9x`)
+
+	entries, err := songs.ReadDelimited(&out, ',', nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, songs.SpotifyTrack, entries[0].Kind)
+	assert.Equal(t, "2fuCquhmrzHpu5xcA1ci9x", entries[0].SpotifyID)
+	assert.Equal(t, "Tyler, The Creator", entries[1].Artist)
+}
+
+// Test that exported JSON keeps artists as a list
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, songs.WriteJSON(&out, "Backup", exportedEntries))
+
+	var document struct {
+		Name   string `json:"name"`
+		Tracks []struct {
+			Name       string   `json:"name"`
+			Artists    []string `json:"artists"`
+			ISRC       string   `json:"isrc"`
+			DurationMs int64    `json:"duration_ms"`
+			SpotifyURL string   `json:"spotify_url"`
+		} `json:"tracks"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+	assert.Equal(t, "Backup", document.Name)
+	assert.Len(t, document.Tracks, 2)
+	assert.Equal(t, []string{"Queen", "David Bowie"}, document.Tracks[0].Artists)
+	assert.Equal(t, int64(248000), document.Tracks[0].DurationMs)
+	assert.Equal(t, "https://open.spotify.com/track/2fuCquhmrzHpu5xcA1ci9x", document.Tracks[0].SpotifyURL)
+	assert.Equal(t, []string{"Tyler, The Creator"}, document.Tracks[1].Artists)
+}
+
+// Test that exported M3U is read back with Spotify IDs, albums and durations
+func TestWriteM3U(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, songs.WriteM3U(&out, "Backup", exportedEntries))
+	assert.Contains(t, out.String(), "#EXTM3U\n#PLAYLIST:Backup\n#EXTINF:248,Queen, David Bowie - Under Pressure\n#EXTALB:Hot Space\n")
+	assert.Contains(t, out.String(), "#EXTINF:-1,")
+
+	entries, err := songs.ReadM3U(&out)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, songs.SpotifyTrack, entries[0].Kind)
+	assert.Equal(t, "2fuCquhmrzHpu5xcA1ci9x", entries[0].SpotifyID)
+	assert.Equal(t, "Under Pressure", entries[0].Title)
+	assert.Equal(t, "Hot Space", entries[0].Album)
+	assert.Equal(t, 248*time.Second, entries[0].Duration)
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"testing"
+	"yt-spotify/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test parsing of pasted redirect URLs and bare codes in headless mode
+func TestParseRedirectInput(t *testing.T) {
+	testCases := []struct {
+		input    string
+		code     string
+		errorMsg string
+	}{
+		{"http://localhost:8087/callback?code=abc123&state=s1", "abc123", ""},
+		{"  https://example.com/cb?state=s1&code=xyz\n", "xyz", ""},
+		{"code=only-query&state=s1", "only-query", ""},
+		{"AQBareCode_123", "AQBareCode_123", ""},
+		{"http://localhost:8087/callback?code=abc123&state=other", "", "state mismatch"},
+		{"http://localhost:8087/callback?error=access_denied&state=s1", "", "access_denied"},
+		{"", "", "no authorization code"},
+	}
+
+	for _, tc := range testCases {
+		code, err := auth.ParseRedirectInput(tc.input, "s1")
+		if tc.errorMsg != "" {
+			assert.ErrorContains(t, err, tc.errorMsg, "input %q", tc.input)
+			continue
+		}
+		assert.NoError(t, err, "input %q", tc.input)
+		assert.Equal(t, tc.code, code, "input %q", tc.input)
+	}
+}
+
+// Test that only redirect URIs on a loopback host are served locally
+func TestIsLocalRedirect(t *testing.T) {
+	testCases := []struct {
+		redirectURI string
+		local       bool
+	}{
+		{"http://localhost:8087/callback", true},
+		{"http://127.0.0.1:8088/callback", true},
+		{"http://[::1]:8088/callback", true},
+		{"http://localhost/callback", true},
+		{"http://localhost.example.com/callback", false},
+		{"http://127.0.0.1.nip.io:8087/callback", false},
+		{"http://localhostfoo:8087/callback", false},
+		{"https://example.com/callback", false},
+		{"https://localhost:8087/callback", false},
+		{"not a url\x7f", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.local, auth.IsLocalRedirect(tc.redirectURI), tc.redirectURI)
+	}
+}
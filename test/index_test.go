@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedTracks serves a playlist of total tracks in pages of 100, where every track ID appears
+// twice and removed tracks come back without an ID.
+type pagedTracks struct {
+	total int
+	pages int
+}
+
+func (f *pagedTracks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/playlists/playlist1/tracks" {
+		http.NotFound(w, r)
+		return
+	}
+	f.pages++
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	var items []interface{}
+	for i := offset; i < offset+100 && i < f.total; i++ {
+		switch {
+		case i%10 == 9:
+			items = append(items, map[string]interface{}{"track": nil})
+		case i%10 == 8:
+			items = append(items, map[string]interface{}{"track": map[string]string{"id": ""}})
+		default:
+			items = append(items, map[string]interface{}{"track": map[string]string{"id": fmt.Sprintf("track%d", i/2)}})
+		}
+	}
+	page := map[string]interface{}{"items": items, "next": nil}
+	if offset+100 < f.total {
+		page["next"] = fmt.Sprintf("https://api.spotify.com/v1/playlists/playlist1/tracks?offset=%d&limit=100", offset+100)
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// Test that the index holds the tracks of every page and that known tracks are not claimed again
+func TestLoadPlaylistIndex_Pages(t *testing.T) {
+	fake := &pagedTracks{total: 250}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	client := &http.Client{Transport: redirectTransport{target: target}}
+
+	index, err := spotify.LoadPlaylistIndex(context.Background(), client, "playlist1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fake.pages)
+	assert.Equal(t, "playlist1", index.PlaylistID())
+
+	assert.True(t, index.Contains("track0"))
+	assert.True(t, index.Contains("track50"), "Track from the second page")
+	assert.True(t, index.Contains("track120"), "Track from the last page")
+	assert.False(t, index.Contains(""), "Removed tracks should not be indexed")
+	assert.False(t, index.Contains("track125"), "Past the end of the playlist")
+
+	assert.False(t, index.TryAdd("track120"), "A track already in the playlist should not be claimed")
+	assert.True(t, index.TryAdd("track125"))
+	assert.False(t, index.TryAdd("track125"), "A new track should only be claimed once")
+	assert.True(t, index.Contains("track125"))
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that the original recording beats karaoke, cover and tribute versions
+func TestMatcher_PrefersOriginalOverVariants(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	query := spotify.TrackQuery{Title: "Bohemian Rhapsody (Official Video)", Artist: "Queen"}
+
+	candidates := []spotify.Candidate{
+		{ID: "karaoke", Name: "Bohemian Rhapsody (Karaoke Version)", Artists: []string{"Karaoke Hits Band"}},
+		{ID: "tribute", Name: "Bohemian Rhapsody", Artists: []string{"Tribute to Queen"}},
+		{ID: "cover", Name: "Bohemian Rhapsody - Cover", Artists: []string{"Some Band"}},
+		{ID: "original", Name: "Bohemian Rhapsody - Remastered 2011", Artists: []string{"Queen"}},
+	}
+
+	match, err := matcher.Best(query, candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "original", match.Candidate.ID)
+	assert.Greater(t, match.Score, 0.9)
+}
+
+// Test that featured artists count towards the artist score
+func TestMatcher_MatchesAnyCreditedArtist(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	query := spotify.TrackQuery{Title: "Under Pressure", Artist: "David Bowie"}
+
+	candidates := []spotify.Candidate{
+		{ID: "other", Name: "Under Pressure", Artists: []string{"My Chemical Romance"}},
+		{ID: "duet", Name: "Under Pressure", Artists: []string{"Queen", "David Bowie"}},
+	}
+
+	match, err := matcher.Best(query, candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "duet", match.Candidate.ID)
+}
+
+// Test that the duration delta separates otherwise identical candidates
+func TestMatcher_UsesDuration(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	query := spotify.TrackQuery{Title: "Strobe", Artist: "deadmau5", Duration: 3*time.Minute + 35*time.Second}
+
+	candidates := []spotify.Candidate{
+		{ID: "extended", Name: "Strobe", Artists: []string{"deadmau5"}, Duration: 10*time.Minute + 37*time.Second},
+		{ID: "radio", Name: "Strobe", Artists: []string{"deadmau5"}, Duration: 3*time.Minute + 33*time.Second},
+	}
+
+	match, err := matcher.Best(query, candidates)
+	assert.NoError(t, err)
+	assert.Equal(t, "radio", match.Candidate.ID)
+}
+
+// Test that weak candidates are rejected with an explanation
+func TestMatcher_RejectsBelowThreshold(t *testing.T) {
+	matcher := spotify.NewMatcher(0.8, 0)
+	query := spotify.TrackQuery{Title: "Blinding Lights", Artist: "The Weeknd"}
+
+	_, err := matcher.Best(query, []spotify.Candidate{
+		{ID: "x", Name: "Blinded by the Light", Artists: []string{"Manfred Mann's Earth Band"}},
+	})
+
+	var noMatch *spotify.NoMatchError
+	assert.True(t, errors.As(err, &noMatch))
+	assert.NotNil(t, noMatch.Best)
+	assert.Contains(t, err.Error(), "below threshold 0.80")
+
+	_, err = matcher.Best(query, nil)
+	assert.ErrorContains(t, err, "no tracks found")
+}
+
+// Test the Jaro-Winkler similarity against known values
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, spotify.JaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, spotify.JaroWinkler("dwayne", "duane"), 0.001)
+	assert.Equal(t, 1.0, spotify.JaroWinkler("queen", "queen"))
+	assert.Equal(t, 0.0, spotify.JaroWinkler("", "queen"))
+}
+
+// Test that a music video running a minute longer than the track does not reject the track
+func TestMatcher_MusicVideoLength(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	track := spotify.Candidate{ID: "track", Name: "Blinding Lights", Artists: []string{"The Weeknd"}, Duration: 3*time.Minute + 20*time.Second}
+
+	withoutLength := spotify.TrackQuery{Title: "Blinding Lights (Official Video)", Artist: "TheWeekndVEVO"}
+	withLength := withoutLength
+	withLength.Duration = 4*time.Minute + 22*time.Second
+
+	// The length only ranks candidates, it does not lower the score
+	assert.Equal(t, matcher.Score(withoutLength, track).Score, matcher.Score(withLength, track).Score)
+
+	match, err := matcher.Best(withLength, []spotify.Candidate{track})
+	assert.NoError(t, err)
+	assert.Equal(t, "track", match.Candidate.ID)
+}
+
+// Test that candidates far outside the duration tolerance lose to one inside it
+func TestMatcher_DurationTolerance(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	query := spotify.TrackQuery{Title: "One More Time", Artist: "Daft Punk", Duration: 5*time.Minute + 20*time.Second}
+
+	teaser := spotify.Candidate{ID: "teaser", Name: "One More Time", Artists: []string{"Daft Punk"}, Duration: time.Minute}
+	album := spotify.Candidate{ID: "album", Name: "One More Time", Artists: []string{"Daft Punk"}, Duration: 5*time.Minute + 20*time.Second}
+
+	assert.Contains(t, matcher.Score(query, teaser).Reasons, "duration outside tolerance")
+
+	match, err := matcher.Best(query, []spotify.Candidate{teaser, album})
+	assert.NoError(t, err)
+	assert.Equal(t, "album", match.Candidate.ID)
+}
+
+// Test that zero selects the default threshold and tolerance and other values are kept
+func TestNewMatcher_Defaults(t *testing.T) {
+	matcher := spotify.NewMatcher(0, 0)
+	assert.Equal(t, spotify.DefaultMatchThreshold, matcher.Threshold)
+	assert.Equal(t, spotify.DefaultDurationTolerance, matcher.DurationTolerance)
+
+	matcher = spotify.NewMatcher(0.8, 5*time.Second)
+	assert.Equal(t, 0.8, matcher.Threshold)
+	assert.Equal(t, 5*time.Second, matcher.DurationTolerance)
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/joho/godotenv"
@@ -20,7 +21,7 @@ func GetConfig() (*config.AppContext, error) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	var playlists []string
+	var playlists []config.PlaylistSource
 	rawPlaylists := os.Getenv("PLAYLISTS")
 	if rawPlaylists != "" {
 		err := json.Unmarshal([]byte(rawPlaylists), &playlists)
@@ -71,7 +72,7 @@ func TestExtractSongArtist_RealMistralAPI(t *testing.T) {
 	}
 
 	// Call the real API
-	song, artist, err := mistralService.ExtractSongArtist("The Weeknd - Blinding Lights (Official Video)")
+	song, artist, err := mistralService.ExtractSongArtist(context.Background(), "The Weeknd - Blinding Lights (Official Video)")
 
 	// Assertions
 	assert.NoError(t, err, "Mistral API call should not fail")
@@ -100,7 +101,7 @@ func TestExtractSongArtist_UnusualInput(t *testing.T) {
 	}
 
 	// Call the real API with an unusual input
-	song, artist, err := mistralService.ExtractSongArtist("This is not a song - Just testing (Live 2025)")
+	song, artist, err := mistralService.ExtractSongArtist(context.Background(), "This is not a song - Just testing (Live 2025)")
 
 	// Assertions
 	assert.NoError(t, err, "Mistral API call should not fail")
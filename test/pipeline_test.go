@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"yt-spotify/pipeline"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrency tracks the highest number of simultaneous calls.
+type concurrency struct {
+	current, peak int32
+}
+
+func (c *concurrency) enter() {
+	n := atomic.AddInt32(&c.current, 1)
+	for {
+		peak := atomic.LoadInt32(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (c *concurrency) leave() {
+	atomic.AddInt32(&c.current, -1)
+}
+
+// Test that results are written in source order while each stage respects its limit
+func TestPipelineRun_OrderAndLimits(t *testing.T) {
+	var extracting, searching concurrency
+	var mu sync.Mutex
+	var emitted, written, maxAhead int
+
+	var output []int
+	pipeline.Run(context.Background(), pipeline.Limits{Extractors: 2, Searchers: 3},
+		func(emit func(int) bool) {
+			for i := 0; i < 50; i++ {
+				emit(i)
+				mu.Lock()
+				emitted++
+				maxAhead = max(maxAhead, emitted-written)
+				mu.Unlock()
+			}
+		},
+		func(i int) int {
+			extracting.enter()
+			defer extracting.leave()
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+			return i * 10
+		},
+		func(i int) int {
+			searching.enter()
+			defer searching.leave()
+			time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+			return i + 1
+		},
+		func(result int) {
+			mu.Lock()
+			written++
+			mu.Unlock()
+			output = append(output, result)
+		},
+	)
+
+	assert.Len(t, output, 50)
+	for i, result := range output {
+		assert.Equal(t, i*10+1, result)
+	}
+	assert.LessOrEqual(t, extracting.peak, int32(2))
+	assert.LessOrEqual(t, searching.peak, int32(3))
+	// The source is held back instead of running ahead of a slow stage
+	assert.LessOrEqual(t, maxAhead, 2*(2+3)+1)
+}
+
+// Test that a slow first value does not let the rest pile up unbounded
+func TestPipelineRun_Backpressure(t *testing.T) {
+	var emitted int32
+	release := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pipeline.Run(context.Background(), pipeline.Limits{Extractors: 1, Searchers: 1},
+			func(emit func(int) bool) {
+				for i := 0; i < 100; i++ {
+					emit(i)
+					atomic.AddInt32(&emitted, 1)
+				}
+			},
+			func(i int) int {
+				if i == 0 {
+					<-release
+				}
+				return i
+			},
+			func(i int) int { return i },
+			func(int) {},
+		)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.LessOrEqual(t, atomic.LoadInt32(&emitted), int32(2*(1+1)))
+	close(release)
+	<-done
+	assert.Equal(t, int32(100), atomic.LoadInt32(&emitted))
+}
+
+// Test that cancelling stops the source and writes an in-order prefix of the results
+func TestPipelineRun_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var output []int
+	stopped := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pipeline.Run(ctx, pipeline.Limits{Extractors: 2, Searchers: 2},
+			func(emit func(int) bool) {
+				for i := 0; ; i++ {
+					if !emit(i) {
+						stopped = true
+						return
+					}
+				}
+			},
+			func(i int) int {
+				if i == 20 {
+					cancel()
+				}
+				time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+				return i
+			},
+			func(i int) int { return i },
+			func(result int) { output = append(output, result) },
+		)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	assert.True(t, stopped)
+	assert.Less(t, len(output), 20+2*(2+2))
+	for i, result := range output {
+		assert.Equal(t, i, result)
+	}
+}
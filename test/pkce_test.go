@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"yt-spotify/auth"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// tokenRequest is what the fake token endpoint received.
+type tokenRequest struct {
+	form      url.Values
+	basicUser string
+	basicPass string
+}
+
+// authorizeAgainst runs the authorization flow for conf against a fake token endpoint and
+// returns the authorization URL the user was sent to and the token request.
+func authorizeAgainst(t *testing.T, conf *oauth2.Config) (*url.URL, tokenRequest) {
+	var received tokenRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		received.form = r.PostForm
+		received.basicUser, received.basicPass, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer server.Close()
+	conf.Endpoint.TokenURL = server.URL
+
+	var authURL *url.URL
+	token, err := auth.AuthorizeWith(context.Background(), conf, "Spotify", func(ctx context.Context, rawURL, state string) (string, error) {
+		authURL, _ = url.Parse(rawURL)
+		assert.Equal(t, state, authURL.Query().Get("state"))
+		return "the-code", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "access", token.AccessToken)
+	assert.Equal(t, "the-code", received.form.Get("code"))
+	return authURL, received
+}
+
+// Test that without a client secret the PKCE flow sends the verifier matching the S256
+// challenge and the client ID in the body, and never a client secret
+func TestAuthorize_PKCE(t *testing.T) {
+	conf := spotify.OAuthConfig(spotify.AuthOptions{ClientID: "client", RedirectURI: "http://127.0.0.1:8087/callback"})
+	assert.True(t, auth.UsePKCE(conf))
+	assert.Equal(t, oauth2.AuthStyleInParams, conf.Endpoint.AuthStyle)
+
+	authURL, received := authorizeAgainst(t, conf)
+
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	verifier := received.form.Get("code_verifier")
+	assert.GreaterOrEqual(t, len(verifier), 43, "RFC 7636 verifiers are at least 43 characters")
+	sum := sha256.Sum256([]byte(verifier))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), authURL.Query().Get("code_challenge"))
+
+	assert.Equal(t, "client", received.form.Get("client_id"))
+	_, hasSecret := received.form["client_secret"]
+	assert.False(t, hasSecret, "A public client has no secret to send")
+	assert.Empty(t, received.basicUser, "The client should not authenticate with basic auth")
+}
+
+// Test that with a client secret the regular flow is used and the secret is sent
+func TestAuthorize_ClientSecret(t *testing.T) {
+	conf := spotify.OAuthConfig(spotify.AuthOptions{ClientID: "client", ClientSecret: "secret", RedirectURI: "http://127.0.0.1:8087/callback"})
+	assert.False(t, auth.UsePKCE(conf))
+	assert.Equal(t, oauth2.AuthStyleAutoDetect, conf.Endpoint.AuthStyle)
+
+	authURL, received := authorizeAgainst(t, conf)
+
+	assert.Empty(t, authURL.Query().Get("code_challenge"))
+	assert.Empty(t, received.form.Get("code_verifier"))
+	secret := received.form.Get("client_secret")
+	if secret == "" {
+		assert.Equal(t, "client", received.basicUser)
+		secret = received.basicPass
+	}
+	assert.Equal(t, "secret", secret)
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"yt-spotify/songs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test M3U import from #EXTINF lines and from bare file paths
+func TestReadM3U(t *testing.T) {
+	data := "\uFEFF#EXTM3U\n" +
+		"#EXTINF:354,Queen - Bohemian Rhapsody\n" +
+		"Queen/A Night at the Opera/11 Bohemian Rhapsody.mp3\n" +
+		"#EXTINF:-1,Teardrop\n" +
+		"/music/Massive Attack/Mezzanine/03%20Teardrop.flac\n" +
+		"C:\\Music\\Daft Punk\\Discovery\\01 - One More Time.mp3\n" +
+		"file:///home/me/Music/Eminem - Lose Yourself.mp3\n"
+
+	entries, err := songs.ReadM3U(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	assert.Equal(t, "Bohemian Rhapsody", entries[0].Title)
+	assert.Equal(t, "Queen", entries[0].Artist)
+	assert.Equal(t, 354*time.Second, entries[0].Duration)
+
+	// Title from EXTINF, artist and album from the path
+	assert.Equal(t, "Teardrop", entries[1].Title)
+	assert.Equal(t, "Massive Attack", entries[1].Artist)
+	assert.Equal(t, "Mezzanine", entries[1].Album)
+	assert.Equal(t, time.Duration(0), entries[1].Duration)
+
+	assert.Equal(t, "One More Time", entries[2].Title)
+	assert.Equal(t, "Daft Punk", entries[2].Artist)
+	assert.Equal(t, "Discovery", entries[2].Album)
+
+	assert.Equal(t, "Lose Yourself", entries[3].Title)
+	assert.Equal(t, "Eminem", entries[3].Artist)
+}
+
+// Test PLS import in entry number order
+func TestReadPLS(t *testing.T) {
+	data := "[playlist]\n" +
+		"File2=Artists/50 Cent/Get Rich or Die Tryin'/07 In Da Club.mp3\n" +
+		"File1=/music/Hurt.mp3\n" +
+		"Title1=Johnny Cash - Hurt\n" +
+		"Length1=218\n" +
+		"NumberOfEntries=2\n" +
+		"Version=2\n"
+
+	entries, err := songs.ReadPLS(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, "Hurt", entries[0].Title)
+	assert.Equal(t, "Johnny Cash", entries[0].Artist)
+	assert.Equal(t, 218*time.Second, entries[0].Duration)
+
+	assert.Equal(t, "In Da Club", entries[1].Title)
+	assert.Equal(t, "50 Cent", entries[1].Artist)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedPlaylists serves the current user's playlists over two pages linked by `next` and
+// records the playlists it creates.
+type pagedPlaylists struct {
+	pages   [][]spotify.Playlist
+	fetched []string
+	created []string
+}
+
+func ownedPlaylist(id, name, owner string) spotify.Playlist {
+	playlist := spotify.Playlist{ID: id, Name: name}
+	playlist.Owner.ID = owner
+	return playlist
+}
+
+func (f *pagedPlaylists) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/me":
+		fmt.Fprint(w, `{"id":"user"}`)
+	case r.Method == "GET" && r.URL.Path == "/v1/me/playlists":
+		offset := r.URL.Query().Get("offset")
+		f.fetched = append(f.fetched, offset)
+		page := map[string]interface{}{"items": f.pages[0], "next": "https://api.spotify.com/v1/me/playlists?offset=50&limit=50"}
+		if offset == "50" {
+			page = map[string]interface{}{"items": f.pages[1], "next": nil}
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == "POST" && r.URL.Path == "/v1/users/user/playlists":
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.created = append(f.created, body.Name)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"created"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newPagedPlaylists(t *testing.T, second []spotify.Playlist) (*pagedPlaylists, *http.Client) {
+	fake := &pagedPlaylists{pages: [][]spotify.Playlist{
+		{ownedPlaylist("first", "Road Trip", "user"), ownedPlaylist("other", "Imported", "someone")},
+		second,
+	}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	return fake, &http.Client{Transport: redirectTransport{target: target}}
+}
+
+// Test that the iterator follows the `next` link to the second page
+func TestPlaylistIterator_FollowsNext(t *testing.T) {
+	fake, client := newPagedPlaylists(t, []spotify.Playlist{ownedPlaylist("second", "Workout", "user")})
+
+	var ids []string
+	it := spotify.NewPlaylistIterator(context.Background(), client)
+	for it.Next() {
+		ids = append(ids, it.Playlist().ID)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"first", "other", "second"}, ids)
+	assert.Equal(t, []string{"", "50"}, fake.fetched)
+}
+
+// Test that an owned playlist on a later page is reused
+func TestCheckOrCreatePlaylist_FindsOnLaterPage(t *testing.T) {
+	fake, client := newPagedPlaylists(t, []spotify.Playlist{ownedPlaylist("mine", "Imported", "user")})
+
+	id, err := spotify.CheckOrCreatePlaylist(context.Background(), client, "Imported", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "mine", id)
+	assert.Empty(t, fake.created)
+}
+
+// Test that playlists with the same name owned by someone else are never reused
+func TestCheckOrCreatePlaylist_SkipsPlaylistsOfOthers(t *testing.T) {
+	fake, client := newPagedPlaylists(t, []spotify.Playlist{ownedPlaylist("followed", "Imported", "someone")})
+
+	id, err := spotify.CheckOrCreatePlaylist(context.Background(), client, "Imported", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "created", id)
+	assert.Equal(t, []string{"", "50"}, fake.fetched, "Both pages should be searched before creating")
+	assert.Equal(t, []string{"Imported"}, fake.created)
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redirectTransport sends every request to a test server instead of api.spotify.com.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// fakeSpotify serves just enough of the playlist API for CheckOrCreatePlaylist and keeps
+// creation slow, so concurrent lookups would all miss a playlist that is still being created.
+type fakeSpotify struct {
+	mu        sync.Mutex
+	playlists []spotify.Playlist
+	created   int
+}
+
+func (f *fakeSpotify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/me":
+		fmt.Fprint(w, `{"id":"user"}`)
+	case r.Method == "GET" && r.URL.Path == "/v1/me/playlists":
+		f.mu.Lock()
+		page := map[string]interface{}{"items": f.playlists, "next": ""}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(page)
+	case r.Method == "POST" && r.URL.Path == "/v1/users/user/playlists":
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		time.Sleep(50 * time.Millisecond)
+
+		f.mu.Lock()
+		f.created++
+		playlist := spotify.Playlist{ID: fmt.Sprintf("playlist%d", f.created), Name: body.Name}
+		playlist.Owner.ID = "user"
+		f.playlists = append(f.playlists, playlist)
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":%q}`, playlist.ID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Test that concurrent callers asking for the same playlist get one playlist
+func TestPlaylistRegistry_CreatesOnce(t *testing.T) {
+	fake := &fakeSpotify{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	registry := spotify.NewPlaylistRegistry(&http.Client{Transport: redirectTransport{target: target}})
+
+	var wg sync.WaitGroup
+	ids := make([]string, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "Imported"
+			if i%2 == 1 {
+				name = "Other"
+			}
+			id, err := registry.PlaylistID(context.Background(), name, "")
+			assert.NoError(t, err)
+			ids[i] = id
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, fake.created)
+	for i := 2; i < len(ids); i++ {
+		assert.Equal(t, ids[i%2], ids[i])
+	}
+	assert.NotEqual(t, ids[0], ids[1])
+}
+
+// Test that the shared index lets only one goroutine claim a track
+func TestPlaylistIndex_TryAdd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items":[{"track":{"id":"existing"}}],"next":""}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	registry := spotify.NewPlaylistRegistry(&http.Client{Transport: redirectTransport{target: target}})
+	index, err := registry.Index(context.Background(), "playlist1")
+	assert.NoError(t, err)
+	again, err := registry.Index(context.Background(), "playlist1")
+	assert.NoError(t, err)
+	assert.Same(t, index, again)
+
+	assert.False(t, index.TryAdd("existing"))
+	assert.True(t, index.TryAdd("new"))
+	assert.False(t, again.TryAdd("new"))
+}
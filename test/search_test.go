@@ -0,0 +1,45 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"yt-spotify/spotify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that only whole noise words are stripped from the search query
+func TestFindTrack_CleansQuery(t *testing.T) {
+	testCases := []struct {
+		title  string
+		artist string
+		query  string
+	}{
+		{"Stayin' Alive", "Bee Gees", "track:Stayin' Alive artist:Bee Gees"},
+		{"Life Goes On", "Oliver Tree", "track:Life Goes On artist:Oliver Tree"},
+		{"Liveline", "Audiomachine", "track:Liveline artist:Audiomachine"},
+		{"Blinding Lights Official Video", "The Weeknd VEVO", "track:Blinding Lights artist:The Weeknd"},
+		{"Hotel California Live", "Eagles", "track:Hotel California artist:Eagles"},
+	}
+
+	for _, tc := range testCases {
+		var queries []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.Query().Get("q"))
+			fmt.Fprint(w, `{"tracks":{"items":[]}}`)
+		}))
+		target, _ := url.Parse(server.URL)
+		client := &http.Client{Transport: redirectTransport{target: target}}
+
+		spotify.FindTrack(context.Background(), client, spotify.NewMatcher(0, 0), spotify.TrackQuery{Title: tc.title, Artist: tc.artist})
+		server.Close()
+
+		if assert.NotEmpty(t, queries, "%s", tc.title) {
+			assert.Equal(t, tc.query, queries[0])
+		}
+	}
+}
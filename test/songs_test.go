@@ -0,0 +1,131 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"yt-spotify/songs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that Spotify links, URIs and ISRC codes are recognized and everything else is free text
+func TestClassifyLine(t *testing.T) {
+	testCases := []struct {
+		line      string
+		kind      songs.EntryKind
+		spotifyID string
+		isrc      string
+	}{
+		{"https://open.spotify.com/track/4u7EnebtmKWzUH433cf5Qv?si=abc", songs.SpotifyTrack, "4u7EnebtmKWzUH433cf5Qv", ""},
+		{"open.spotify.com/intl-de/track/4u7EnebtmKWzUH433cf5Qv", songs.SpotifyTrack, "4u7EnebtmKWzUH433cf5Qv", ""},
+		{"spotify:track:4u7EnebtmKWzUH433cf5Qv", songs.SpotifyTrack, "4u7EnebtmKWzUH433cf5Qv", ""},
+		{"GBUM71029604", songs.ISRC, "", "GBUM71029604"},
+		{"isrc: gb-um7-10-29604", songs.ISRC, "", "GBUM71029604"},
+		{"Queen Bohemian Rhapsody", songs.FreeText, "", ""},
+		{"https://open.spotify.com/album/4u7EnebtmKWzUH433cf5Qv", songs.FreeText, "", ""},
+	}
+
+	for _, tc := range testCases {
+		entry := songs.ClassifyLine(tc.line)
+		assert.Equal(t, tc.kind, entry.Kind, tc.line)
+		assert.Equal(t, tc.spotifyID, entry.SpotifyID, tc.line)
+		assert.Equal(t, tc.isrc, entry.ISRC, tc.line)
+	}
+}
+
+// Test splitting lines into title and artist
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		line   string
+		title  string
+		artist string
+	}{
+		{"Queen - Bohemian Rhapsody", "Bohemian Rhapsody", "Queen"},
+		{"01. Daft Punk – One More Time", "One More Time", "Daft Punk"},
+		{"3) Eminem | Lose Yourself [05:26]", "Lose Yourself", "Eminem"},
+		{"Massive Attack\tTeardrop", "Teardrop", "Massive Attack"},
+		{"Hurt by Johnny Cash", "Hurt", "Johnny Cash"},
+		{"Stand by Me by Ben E. King", "Stand by Me", "Ben E. King"},
+		{"(03:12) Blinding Lights", "Blinding Lights", ""},
+	}
+
+	for _, tc := range testCases {
+		entry, ok := songs.ParseLine(tc.line)
+		assert.True(t, ok, tc.line)
+		assert.Equal(t, songs.FreeText, entry.Kind, tc.line)
+		assert.Equal(t, tc.title, entry.Title, tc.line)
+		assert.Equal(t, tc.artist, entry.Artist, tc.line)
+	}
+
+	for _, skipped := range []string{"", "   ", "# my favourites", "\r"} {
+		_, ok := songs.ParseLine(skipped)
+		assert.False(t, ok, "%q should be skipped", skipped)
+	}
+
+	// " by " may be part of the title and a bare title cannot be told from "Title by Artist",
+	// so the whole line is kept to search when the split finds nothing
+	entry, ok := songs.ParseLine("Stand by Me")
+	assert.True(t, ok)
+	assert.Equal(t, "Stand", entry.Title)
+	assert.Equal(t, "Me", entry.Artist)
+	assert.Equal(t, "Stand by Me", entry.Unsplit)
+
+	for _, line := range []string{"Ben E. King - Stand by Me", "Ben E. King: Stand by Me"} {
+		entry, ok = songs.ParseLine(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, "", entry.Unsplit, line)
+		assert.NotEqual(t, "Me", entry.Artist, line)
+	}
+	entry, _ = songs.ParseLine("Ben E. King - Stand by Me")
+	assert.Equal(t, "Stand by Me", entry.Title)
+
+	entry, ok = songs.ParseLine("02. spotify:track:4u7EnebtmKWzUH433cf5Qv")
+	assert.True(t, ok)
+	assert.Equal(t, songs.SpotifyTrack, entry.Kind)
+}
+
+// Test CSV import with a detected header row
+func TestReadDelimited_DetectsHeader(t *testing.T) {
+	data := "Track Name,Artist Name(s),Album Name,ISRC\n" +
+		"Bohemian Rhapsody,Queen,A Night at the Opera,GBUM71029604\n" +
+		"Teardrop,Massive Attack,Mezzanine,\n" +
+		",,,\n"
+
+	entries, err := songs.ReadDelimited(strings.NewReader(data), ',', nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, songs.ISRC, entries[0].Kind)
+	assert.Equal(t, "GBUM71029604", entries[0].ISRC)
+	assert.Equal(t, "Bohemian Rhapsody", entries[0].Title)
+	assert.Equal(t, "A Night at the Opera", entries[0].Album)
+
+	assert.Equal(t, songs.FreeText, entries[1].Kind)
+	assert.Equal(t, "Massive Attack", entries[1].Artist)
+}
+
+// Test TSV import with an explicit column mapping
+func TestReadDelimited_ColumnMapping(t *testing.T) {
+	mapping, err := songs.ParseColumnMapping("title=Song, artist=Performer")
+	assert.NoError(t, err)
+
+	data := "Performer\tSong\tRating\nDaft Punk\tOne More Time\t5\n"
+	entries, err := songs.ReadDelimited(strings.NewReader(data), '\t', mapping)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "One More Time", entries[0].Title)
+	assert.Equal(t, "Daft Punk", entries[0].Artist)
+
+	// Column numbers work for files without a header
+	mapping, err = songs.ParseColumnMapping("artist=1,title=2")
+	assert.NoError(t, err)
+	entries, err = songs.ReadDelimited(strings.NewReader("Eminem\tLose Yourself\n"), '\t', mapping)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Lose Yourself", entries[0].Title)
+
+	_, err = songs.ParseColumnMapping("rating=3")
+	assert.Error(t, err)
+	_, err = songs.ReadDelimited(strings.NewReader("A,B\n"), ',', songs.ColumnMapping{"title": "Song"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"yt-spotify/state"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that import progress survives a save/load round trip and that --fresh ignores it
+func TestStateStore_SaveLoadFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import_state.json")
+
+	store, err := state.Open(path, false)
+	assert.NoError(t, err)
+	assert.Equal(t, state.Video{}, store.Video("playlist PL1", "abc"), "Nothing should be saved yet")
+
+	store.Update("playlist PL1", "abc", func(video *state.Video) {
+		video.Extracted = true
+		video.Tracks = []state.Track{{Title: "Blinding Lights", Artist: "The Weeknd", Duration: 200 * time.Second, Searched: true, SpotifyID: "0VjIjW4GlUZAMYd2vXMi3b"}}
+		video.AddedTo = "spotifyPlaylist"
+	})
+	store.Update("playlist PL1", "def", func(video *state.Video) {
+		video.Extracted = true
+		video.Tracks = []state.Track{{Title: "Unknown", Searched: true}}
+	})
+	assert.NoError(t, store.Save())
+
+	reopened, err := state.Open(path, false)
+	assert.NoError(t, err)
+	saved := reopened.Video("playlist PL1", "abc")
+	assert.True(t, saved.Searched())
+	assert.Equal(t, "spotifyPlaylist", saved.AddedTo)
+	assert.Equal(t, "0VjIjW4GlUZAMYd2vXMi3b", saved.Tracks[0].SpotifyID)
+	assert.Equal(t, 200*time.Second, saved.Tracks[0].Duration)
+	assert.Equal(t, "", reopened.Video("playlist PL1", "def").Tracks[0].SpotifyID, "A definite miss should be kept")
+	assert.Equal(t, state.Video{}, reopened.Video("playlist PL2", "abc"), "Progress is kept per source playlist")
+
+	fresh, err := state.Open(path, true)
+	assert.NoError(t, err)
+	assert.Equal(t, state.Video{}, fresh.Video("playlist PL1", "abc"))
+}
+
+// Test that a video only counts as searched once every extracted track was
+func TestStateVideo_Searched(t *testing.T) {
+	assert.False(t, state.Video{}.Searched(), "Not extracted yet")
+	assert.True(t, state.Video{Extracted: true}.Searched(), "A video without tracks has nothing to search")
+	assert.False(t, state.Video{Extracted: true, Tracks: []state.Track{{Searched: true}, {}}}.Searched())
+	assert.True(t, state.Video{Extracted: true, Tracks: []state.Track{{Searched: true}, {Searched: true}}}.Searched())
+}
+
+// Test that the returned progress is a copy and that a corrupt file is reported
+func TestStateStore_CopyAndCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import_state.json")
+
+	store, err := state.Open(path, false)
+	assert.NoError(t, err)
+	store.Update("playlist PL1", "abc", func(video *state.Video) {
+		video.Tracks = []state.Track{{Title: "Song"}}
+	})
+	video := store.Video("playlist PL1", "abc")
+	video.Tracks[0].Searched = true
+	assert.False(t, store.Video("playlist PL1", "abc").Tracks[0].Searched)
+
+	assert.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+	_, err = state.Open(path, false)
+	assert.Error(t, err)
+	_, err = state.Open(path, true)
+	assert.NoError(t, err, "--fresh should not read the file")
+}
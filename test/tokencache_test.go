@@ -0,0 +1,60 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+	"yt-spotify/auth"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// Test that a token survives a save/load round trip and is stored with private permissions
+func TestTokenCache_SaveLoadDelete(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cache, err := auth.NewTokenCache("test_token.json")
+	assert.NoError(t, err)
+
+	token, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, token, "Nothing should be cached yet")
+
+	saved := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+	assert.NoError(t, cache.Save(saved))
+
+	info, err := os.Stat(cache.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, saved.AccessToken, loaded.AccessToken)
+	assert.Equal(t, saved.RefreshToken, loaded.RefreshToken)
+	assert.True(t, saved.Expiry.Equal(loaded.Expiry))
+
+	assert.NoError(t, cache.Delete())
+	assert.NoError(t, cache.Delete(), "Deleting a missing cache should not fail")
+}
+
+// Test that refreshed tokens are written back to the cache
+func TestTokenCache_TokenSourcePersistsNewTokens(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cache, err := auth.NewTokenCache("test_token.json")
+	assert.NoError(t, err)
+
+	refreshed := &oauth2.Token{AccessToken: "new-access", RefreshToken: "refresh"}
+	tokenSource := cache.TokenSource(oauth2.StaticTokenSource(refreshed))
+
+	token, err := tokenSource.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "new-access", token.AccessToken)
+
+	loaded, err := cache.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "new-access", loaded.AccessToken)
+}
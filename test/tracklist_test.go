@@ -0,0 +1,66 @@
+package test
+
+import (
+	"testing"
+	"time"
+	"yt-spotify/youtube"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test tracklist detection in mix descriptions with leading and trailing timestamps
+func TestParseTracklist(t *testing.T) {
+	description := `Best of 80s mix, enjoy!
+Follow me on Instagram
+
+Tracklist:
+00:00 Queen - Bohemian Rhapsody
+05:55 - a-ha - Take On Me
+1. [09:42] Toto - Africa
+ID - ID 14:10
+1:02:03 Never Gonna Give You Up by Rick Astley
+1:05:40 Stand by Me`
+
+	tracks := youtube.ParseTracklist(description)
+	assert.Equal(t, []youtube.TracklistTrack{
+		{Start: 0, Title: "Bohemian Rhapsody", Artist: "Queen"},
+		{Start: 5*time.Minute + 55*time.Second, Title: "Take On Me", Artist: "a-ha"},
+		{Start: 9*time.Minute + 42*time.Second, Title: "Africa", Artist: "Toto"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second, Title: "Never Gonna Give You Up", Artist: "Rick Astley", Unsplit: "Never Gonna Give You Up by Rick Astley"},
+		{Start: time.Hour + 5*time.Minute + 40*time.Second, Title: "Stand", Artist: "Me", Unsplit: "Stand by Me"},
+	}, tracks)
+
+	trailing := "Queen - Bohemian Rhapsody (0:00)\nToto - Africa (5:55)\nJourney - Don't Stop Believin' (10:12)"
+	tracks = youtube.ParseTracklist(trailing)
+	assert.Len(t, tracks, 3)
+	assert.Equal(t, "Don't Stop Believin'", tracks[2].Title)
+	assert.Equal(t, 10*time.Minute+12*time.Second, tracks[2].Start)
+}
+
+// Test that song lengths and a couple of chapters are not taken for a tracklist
+func TestParseTracklistRejects(t *testing.T) {
+	assert.Nil(t, youtube.ParseTracklist("0:00 Intro\n1:20 Chorus"))
+	assert.Nil(t, youtube.ParseTracklist("Queen - Bohemian Rhapsody 5:55\nToto - Africa 4:55\na-ha - Take On Me 3:48"))
+	assert.Nil(t, youtube.ParseTracklist("No timestamps here\nJust a description"))
+	// Song lengths that happen to go up are not offsets into the video
+	assert.Nil(t, youtube.ParseTracklist("Song A 3:45\nSong B 4:10\nSong C 5:01"))
+	assert.Nil(t, youtube.ParseTracklist("Queen - Bohemian Rhapsody 3:45\nToto - Africa 4:10\na-ha - Take On Me 5:01"))
+}
+
+// Test that chapters without an artist are not searched as tracks
+func TestParseTracklistSkipsChapters(t *testing.T) {
+	description := `0:00 Intro
+0:45 Queen - Bohemian Rhapsody
+6:40 Toto - Africa
+11:35 Interlude
+12:10 a-ha - Take On Me
+16:00 Outro
+16:30 DJ Set - Outro`
+
+	tracks := youtube.ParseTracklist(description)
+	assert.Equal(t, []youtube.TracklistTrack{
+		{Start: 45 * time.Second, Title: "Bohemian Rhapsody", Artist: "Queen"},
+		{Start: 6*time.Minute + 40*time.Second, Title: "Africa", Artist: "Toto"},
+		{Start: 12*time.Minute + 10*time.Second, Title: "Take On Me", Artist: "a-ha"},
+	}, tracks)
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"yt-spotify/importer"
+	"yt-spotify/spotify"
+	"yt-spotify/state"
+	"yt-spotify/youtube"
+
+	"github.com/stretchr/testify/assert"
+	youtubeV3 "google.golang.org/api/youtube/v3"
+)
+
+// fakeAdder records the URIs it is asked to add and adds at most limit of them per call,
+// or all of them when limit is negative.
+type fakeAdder struct {
+	calls [][]string
+	limit int
+}
+
+func (f *fakeAdder) add(ctx context.Context, playlistID string, uris []string) int {
+	f.calls = append(f.calls, append([]string(nil), uris...))
+	if f.limit >= 0 && f.limit < len(uris) {
+		return f.limit
+	}
+	return len(uris)
+}
+
+func writerJob(target *importer.Target, videoID string, video state.Video, uris ...string) *importer.Job {
+	item := &youtube.PlaylistItem{PlaylistItem: &youtubeV3.PlaylistItem{Snippet: &youtubeV3.PlaylistItemSnippet{Title: videoID}}}
+	return &importer.Job{Target: target, Item: item, Source: "playlist PL1", VideoID: videoID, Video: video, URIs: uris}
+}
+
+var searchedVideo = state.Video{Extracted: true, Tracks: []state.Track{{Searched: true}}}
+
+func openWriterStore(t *testing.T) *state.Store {
+	store, err := state.Open(filepath.Join(t.TempDir(), "import_state.json"), false)
+	assert.NoError(t, err)
+	return store
+}
+
+// Test that items an earlier run imported are skipped and the rest are added and recorded
+func TestWriter_SkipsImported(t *testing.T) {
+	store := openWriterStore(t)
+	adder := &fakeAdder{limit: -1}
+	writer := importer.NewWriter(context.Background(), adder.add, store)
+	target := &importer.Target{ID: "spotifyPlaylist"}
+
+	imported := searchedVideo
+	imported.AddedTo = "spotifyPlaylist"
+	writer.Write(writerJob(target, "old", imported, "spotify:track:old"))
+	writer.Write(writerJob(target, "new", searchedVideo, "spotify:track:new"))
+	writer.FlushAll()
+
+	assert.Equal(t, [][]string{{"spotify:track:new"}}, adder.calls)
+	assert.Equal(t, 2, writer.Items)
+	assert.Equal(t, 1, writer.Resumed)
+	assert.Equal(t, 1, writer.Added)
+	assert.Equal(t, "spotifyPlaylist", store.Video("playlist PL1", "new").AddedTo)
+}
+
+// Test that an item with a failed search is added but not recorded as imported
+func TestWriter_FailedSearchNotRecorded(t *testing.T) {
+	store := openWriterStore(t)
+	adder := &fakeAdder{limit: -1}
+	writer := importer.NewWriter(context.Background(), adder.add, store)
+	target := &importer.Target{ID: "spotifyPlaylist"}
+
+	failed := state.Video{Extracted: true, Tracks: []state.Track{{Searched: true, SpotifyID: "found"}, {}}}
+	writer.Write(writerJob(target, "failed", failed, "spotify:track:found"))
+	writer.Write(writerJob(target, "missed", searchedVideo))
+	writer.FlushAll()
+
+	assert.Equal(t, 1, writer.Added)
+	assert.Equal(t, "", store.Video("playlist PL1", "failed").AddedTo, "The failed search should be retried by the next run")
+	assert.Equal(t, "spotifyPlaylist", store.Video("playlist PL1", "missed").AddedTo)
+}
+
+// Test that when only part of a batch is added, the items whose tracks did not make it stay
+// pending and are added by the next flush
+func TestWriter_PartialFailureKeepsPending(t *testing.T) {
+	store := openWriterStore(t)
+	adder := &fakeAdder{limit: 3}
+	writer := importer.NewWriter(context.Background(), adder.add, store)
+	target := &importer.Target{ID: "spotifyPlaylist"}
+
+	writer.Write(writerJob(target, "a", searchedVideo, "a1", "a2"))
+	writer.Write(writerJob(target, "b", searchedVideo, "b1", "b2"))
+	writer.Write(writerJob(target, "c", searchedVideo, "c1"))
+	writer.FlushAll()
+
+	assert.Equal(t, 3, writer.Added)
+	assert.Equal(t, "spotifyPlaylist", store.Video("playlist PL1", "a").AddedTo)
+	assert.Equal(t, "", store.Video("playlist PL1", "b").AddedTo, "b2 was not added")
+	assert.Equal(t, "", store.Video("playlist PL1", "c").AddedTo)
+
+	adder.limit = -1
+	writer.FlushAll()
+	assert.Equal(t, []string{"b2", "c1"}, adder.calls[1], "Only the tracks not added yet should be sent again")
+	assert.Equal(t, 5, writer.Added)
+	assert.Equal(t, "spotifyPlaylist", store.Video("playlist PL1", "b").AddedTo)
+	assert.Equal(t, "spotifyPlaylist", store.Video("playlist PL1", "c").AddedTo)
+}
+
+// failingTransport fails every request, so a test notices any search it did not expect.
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("unexpected request")
+}
+
+// Test that tracks searched by an earlier run reuse their match instead of searching again
+func TestMatchTrack_ReusesStoredMatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items":[{"track":{"id":"existing"}}],"next":""}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	index, err := spotify.LoadPlaylistIndex(context.Background(), &http.Client{Transport: redirectTransport{target: target}}, "playlist1")
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: failingTransport{}}
+	matcher := spotify.NewMatcher(0, 0)
+
+	stored := state.Track{Title: "Blinding Lights", Artist: "The Weeknd", Searched: true, SpotifyID: "0VjIjW4GlUZAMYd2vXMi3b"}
+	uri, ok := importer.MatchTrack(context.Background(), client, matcher, index, &stored)
+	assert.True(t, ok)
+	assert.Equal(t, "spotify:track:0VjIjW4GlUZAMYd2vXMi3b", uri)
+
+	miss := state.Track{Title: "Unknown", Searched: true}
+	_, ok = importer.MatchTrack(context.Background(), client, matcher, index, &miss)
+	assert.False(t, ok, "A stored miss should not be searched again")
+
+	existing := state.Track{Title: "Existing", Searched: true, SpotifyID: "existing"}
+	_, ok = importer.MatchTrack(context.Background(), client, matcher, index, &existing)
+	assert.False(t, ok, "A match already in the playlist should not be added")
+
+	unsearched := state.Track{Title: "New Song", Artist: "Someone"}
+	_, ok = importer.MatchTrack(context.Background(), client, matcher, index, &unsearched)
+	assert.False(t, ok)
+	assert.False(t, unsearched.Searched, "A failed request is not a definite miss")
+}
+
+// Test that a " by " split that finds nothing is searched again as a whole title
+func TestMatchTrack_SearchesUnsplitTitle(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/search" {
+			query := r.URL.Query().Get("q")
+			queries = append(queries, query)
+			if strings.Contains(query, "Stand by Me") {
+				fmt.Fprint(w, `{"tracks":{"items":[{"id":"standbyme","name":"Stand by Me","artists":[{"name":"Ben E. King"}]}]}}`)
+				return
+			}
+			fmt.Fprint(w, `{"tracks":{"items":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[],"next":""}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	client := &http.Client{Transport: redirectTransport{target: target}}
+	index, err := spotify.LoadPlaylistIndex(context.Background(), client, "playlist1")
+	assert.NoError(t, err)
+
+	track := state.Track{Title: "Stand", Artist: "Me", Unsplit: "Stand by Me"}
+	uri, ok := importer.MatchTrack(context.Background(), client, spotify.NewMatcher(0, 0), index, &track)
+	assert.True(t, ok)
+	assert.Equal(t, "spotify:track:standbyme", uri)
+	assert.Equal(t, "standbyme", track.SpotifyID)
+	assert.Contains(t, queries[0], "artist:Me", "The split should be searched first")
+}
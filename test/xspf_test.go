@@ -0,0 +1,88 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"yt-spotify/songs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test reading an XSPF playlist with identifiers, durations and a path-only track
+func TestReadXSPF(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track>
+      <title>Bohemian Rhapsody</title>
+      <creator>Queen</creator>
+      <album>A Night at the Opera</album>
+      <duration>354000</duration>
+      <identifier>isrc:GBUM71029604</identifier>
+    </track>
+    <track>
+      <location>https://open.spotify.com/track/4u7EnebtmKWzUH433cf5Qv</location>
+    </track>
+    <track>
+      <location>file:///music/Massive%20Attack/Mezzanine/03%20Teardrop.flac</location>
+    </track>
+  </trackList>
+</playlist>`
+
+	entries, err := songs.ReadXSPF(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	assert.Equal(t, songs.ISRC, entries[0].Kind)
+	assert.Equal(t, "GBUM71029604", entries[0].ISRC)
+	assert.Equal(t, "Queen", entries[0].Artist)
+	assert.Equal(t, "A Night at the Opera", entries[0].Album)
+	assert.Equal(t, 354*time.Second, entries[0].Duration)
+
+	assert.Equal(t, songs.SpotifyTrack, entries[1].Kind)
+	assert.Equal(t, "4u7EnebtmKWzUH433cf5Qv", entries[1].SpotifyID)
+
+	assert.Equal(t, "Teardrop", entries[2].Title)
+	assert.Equal(t, "Massive Attack", entries[2].Artist)
+}
+
+// Test that exported XSPF and JSPF playlists read back to the same entries
+func TestXSPFAndJSPFRoundTrip(t *testing.T) {
+	exported := []songs.Entry{{
+		Kind:      songs.SpotifyTrack,
+		Title:     "Under Pressure",
+		Artist:    "Queen, David Bowie",
+		Album:     "Hot Space",
+		SpotifyID: "2fuCquhmrzHpu5xcA1ci9x",
+		ISRC:      "GBUM71108206",
+		Duration:  248 * time.Second,
+	}}
+
+	var xspf bytes.Buffer
+	assert.NoError(t, songs.WriteXSPF(&xspf, "Backup", exported))
+	assert.Contains(t, xspf.String(), `xmlns="http://xspf.org/ns/0/"`)
+	assert.Contains(t, xspf.String(), "<title>Backup</title>")
+
+	entries, err := songs.ReadXSPF(&xspf)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, exported[0].SpotifyID, entries[0].SpotifyID)
+	assert.Equal(t, exported[0].Artist, entries[0].Artist)
+	assert.Equal(t, exported[0].Duration, entries[0].Duration)
+
+	var jspf bytes.Buffer
+	assert.NoError(t, songs.WriteJSPF(&jspf, "Backup", exported))
+	entries, err = songs.ReadJSPF(&jspf)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, exported[0].SpotifyID, entries[0].SpotifyID)
+	assert.Equal(t, exported[0].Album, entries[0].Album)
+
+	// Single string locations as written by some exporters
+	entries, err = songs.ReadJSPF(strings.NewReader(`{"playlist":{"track":[{"title":"Hurt","creator":"Johnny Cash","location":"file:///hurt.mp3"}]}}`))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Johnny Cash", entries[0].Artist)
+}
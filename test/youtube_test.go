@@ -0,0 +1,114 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"yt-spotify/youtube"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+	youtubeV3 "google.golang.org/api/youtube/v3"
+)
+
+// Test parsing of the ISO-8601 durations returned by videos.list
+func TestParseISODuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"PT4M13S", 4*time.Minute + 13*time.Second},
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+		{"PT45S", 45 * time.Second},
+		{"PT10M", 10 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P0D", 0},
+	}
+
+	for _, tc := range testCases {
+		duration, err := youtube.ParseISODuration(tc.value)
+		assert.NoError(t, err, tc.value)
+		assert.Equal(t, tc.expected, duration, tc.value)
+	}
+
+	for _, invalid := range []string{"", "PT", "4:13", "PT4X"} {
+		_, err := youtube.ParseISODuration(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func searchResult(videoID, channel string) *youtubeV3.SearchResult {
+	return &youtubeV3.SearchResult{
+		Id:      &youtubeV3.ResourceId{VideoId: videoID},
+		Snippet: &youtubeV3.SearchResultSnippet{ChannelTitle: channel},
+	}
+}
+
+// Test that official Topic uploads win over YouTube's ranking
+func TestBestSearchResult(t *testing.T) {
+	results := []*youtubeV3.SearchResult{
+		{Id: &youtubeV3.ResourceId{ChannelId: "UC123"}, Snippet: &youtubeV3.SearchResultSnippet{}},
+		searchResult("lyrics", "Lyrics Channel"),
+		searchResult("other-topic", "Queen & David Bowie - Topic"),
+		searchResult("topic", "Queen - Topic"),
+	}
+
+	assert.Equal(t, "topic", youtube.BestSearchResult(results, "queen").Id.VideoId)
+	assert.Equal(t, "other-topic", youtube.BestSearchResult(results, "David Bowie").Id.VideoId)
+	assert.Equal(t, "lyrics", youtube.BestSearchResult(results[:2], "Queen").Id.VideoId)
+	assert.Nil(t, youtube.BestSearchResult(results[:1], "Queen"))
+}
+
+// Test detection of an exhausted API quota through wrapped errors
+func TestIsQuotaExceeded(t *testing.T) {
+	quotaErr := &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}
+	assert.True(t, youtube.IsQuotaExceeded(fmt.Errorf("search failed: %w", quotaErr)))
+	assert.False(t, youtube.IsQuotaExceeded(&googleapi.Error{Code: 404, Errors: []googleapi.ErrorItem{{Reason: "playlistNotFound"}}}))
+	assert.False(t, youtube.IsQuotaExceeded(nil))
+}
+
+// Test parsing of playlist, video and channel sources
+func TestParseSource(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected youtube.Source
+	}{
+		{"PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", youtube.Source{Kind: youtube.PlaylistSource, ID: "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"}},
+		{"LL", youtube.Source{Kind: youtube.PlaylistSource, ID: "LL"}},
+		{"https://www.youtube.com/playlist?list=PL123", youtube.Source{Kind: youtube.PlaylistSource, ID: "PL123"}},
+		{"youtube.com/playlist?list=PL123", youtube.Source{Kind: youtube.PlaylistSource, ID: "PL123"}},
+		{"https://music.youtube.com/playlist?list=OLAK5uy_abc&si=x", youtube.Source{Kind: youtube.PlaylistSource, ID: "OLAK5uy_abc"}},
+		{"https://music.youtube.com/browse/VLPL123", youtube.Source{Kind: youtube.PlaylistSource, ID: "PL123"}},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123&index=2", youtube.Source{Kind: youtube.PlaylistSource, ID: "PL123"}},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=RDdQw4w9WgXcQ", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}},
+		{"@LofiGirl", youtube.Source{Kind: youtube.ChannelSource, Handle: "LofiGirl"}},
+		{"https://www.youtube.com/@LofiGirl/videos", youtube.Source{Kind: youtube.ChannelSource, Handle: "LofiGirl"}},
+		{"https://www.youtube.com/channel/UCSJ4gkVC6NrvII8umztf0Ow", youtube.Source{Kind: youtube.ChannelSource, ID: "UCSJ4gkVC6NrvII8umztf0Ow"}},
+		{"https://music.youtube.com/channel/UCSJ4gkVC6NrvII8umztf0Ow", youtube.Source{Kind: youtube.ChannelSource, ID: "UCSJ4gkVC6NrvII8umztf0Ow"}},
+		{"UCSJ4gkVC6NrvII8umztf0Ow", youtube.Source{Kind: youtube.ChannelSource, ID: "UCSJ4gkVC6NrvII8umztf0Ow"}},
+		{"https://www.youtube.com/user/queenofficial", youtube.Source{Kind: youtube.ChannelSource, Username: "queenofficial"}},
+	}
+
+	for _, testCase := range testCases {
+		source, err := youtube.ParseSource(testCase.input)
+		assert.NoError(t, err, testCase.input)
+		assert.Equal(t, testCase.expected, source, testCase.input)
+	}
+
+	for _, invalid := range []string{"", "https://vimeo.com/123", "https://www.youtube.com/watch?v=short", "https://www.youtube.com/feed/library"} {
+		_, err := youtube.ParseSource(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// Test the links written into playlist descriptions
+func TestSourceURL(t *testing.T) {
+	assert.Equal(t, "https://www.youtube.com/playlist?list=PL123", youtube.Source{Kind: youtube.PlaylistSource, ID: "PL123"}.URL())
+	assert.Equal(t, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", youtube.Source{Kind: youtube.VideoSource, ID: "dQw4w9WgXcQ"}.URL())
+	assert.Equal(t, "https://www.youtube.com/@LofiGirl", youtube.Source{Kind: youtube.ChannelSource, Handle: "LofiGirl"}.URL())
+	assert.Equal(t, "https://www.youtube.com/channel/UCSJ4gkVC6NrvII8umztf0Ow", youtube.Source{Kind: youtube.ChannelSource, ID: "UCSJ4gkVC6NrvII8umztf0Ow"}.URL())
+}
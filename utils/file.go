@@ -0,0 +1,17 @@
+package utils
+
+import "os"
+
+// WriteFileAtomic writes data to path through a temporary file renamed into place, so a crash
+// or an interrupted write never leaves a truncated file behind.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+	"yt-spotify/auth"
+)
+
+const (
+	// tokenCacheFile is the name of the cached YouTube token inside the user config directory.
+	tokenCacheFile = "youtube_token.json"
+
+	// DefaultRedirectURI is used when YOUTUBE_REDIRECT_URI is not set. Google accepts any
+	// loopback port for desktop app clients.
+	DefaultRedirectURI = "http://127.0.0.1:8088/callback"
+)
+
+// AuthOptions configures how Authenticate obtains a YouTube token.
+type AuthOptions struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	// Headless prints the authorization URL instead of opening a browser and reads the
+	// redirect URL or code from stdin.
+	Headless bool
+}
+
+// Authenticate runs the installed app OAuth flow for the YouTube Data API and returns a service
+// acting as the signed in user, which is required to create playlists and insert videos.
+// The token is cached next to the Spotify token and refreshed on later runs. ctx aborts the sign-in.
+func Authenticate(ctx context.Context, opts AuthOptions) (*youtube.Service, error) {
+	if opts.ClientID == "" {
+		return nil, fmt.Errorf("YOUTUBE_CLIENT_ID is required to sign in to YouTube")
+	}
+	redirectURI := opts.RedirectURI
+	if redirectURI == "" {
+		redirectURI = DefaultRedirectURI
+	}
+
+	conf := &oauth2.Config{
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		RedirectURL:  redirectURI,
+		Scopes:       []string{youtube.YoutubeScope},
+		Endpoint:     endpoints.Google,
+	}
+
+	cache, err := auth.NewTokenCache(tokenCacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource, err := auth.CachedTokenSource(ctx, conf, cache, "YouTube", opts.Headless)
+	if err != nil {
+		return nil, err
+	}
+	return youtube.NewService(ctx, option.WithTokenSource(tokenSource))
+}
+
+// Logout deletes the cached YouTube token so the next run authenticates again.
+func Logout() error {
+	cache, err := auth.NewTokenCache(tokenCacheFile)
+	if err != nil {
+		return err
+	}
+	if err := cache.Delete(); err != nil {
+		return err
+	}
+	fmt.Println("Removed cached YouTube token", cache.Path())
+	return nil
+}
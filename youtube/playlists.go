@@ -0,0 +1,159 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// topicSuffix marks the auto-generated "Artist - Topic" channels that carry official audio uploads.
+const topicSuffix = " - Topic"
+
+// searchResults is how many search results are considered per track.
+const searchResults = 10
+
+// SearchVideo searches YouTube for a track and returns the ID of the best video, preferring
+// uploads from the artist's official "- Topic" channel. Each call costs 100 quota units.
+func SearchVideo(ctx context.Context, service *youtube.Service, title, artist string) (string, error) {
+	query := strings.TrimSpace(artist + " " + title)
+	response, err := service.Search.List([]string{"snippet"}).Q(query).Type("video").
+		VideoCategoryId("10").MaxResults(searchResults).
+		Fields("items(id/videoId,snippet(title,channelTitle))").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("YouTube search for '%s' failed: %w", query, err)
+	}
+
+	best := BestSearchResult(response.Items, artist)
+	if best == nil {
+		return "", fmt.Errorf("no YouTube video found for '%s'", query)
+	}
+	return best.Id.VideoId, nil
+}
+
+// BestSearchResult picks the video from the artist's own Topic channel, then any Topic channel,
+// and otherwise keeps YouTube's ranking.
+func BestSearchResult(results []*youtube.SearchResult, artist string) *youtube.SearchResult {
+	var videos []*youtube.SearchResult
+	for _, result := range results {
+		if result.Id != nil && result.Id.VideoId != "" && result.Snippet != nil {
+			videos = append(videos, result)
+		}
+	}
+	if len(videos) == 0 {
+		return nil
+	}
+
+	var anyTopic *youtube.SearchResult
+	for _, video := range videos {
+		channel, isTopic := strings.CutSuffix(video.Snippet.ChannelTitle, topicSuffix)
+		if !isTopic {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(channel), strings.TrimSpace(artist)) {
+			return video
+		}
+		if anyTopic == nil {
+			anyTopic = video
+		}
+	}
+	if anyTopic != nil {
+		return anyTopic
+	}
+	return videos[0]
+}
+
+// CheckOrCreatePlaylist returns the ID of the signed in user's playlist called title,
+// creating a private one when there is none yet.
+func CheckOrCreatePlaylist(ctx context.Context, service *youtube.Service, title string) (string, error) {
+	nextPageToken := ""
+	for {
+		response, err := service.Playlists.List([]string{"snippet"}).Mine(true).MaxResults(50).
+			PageToken(nextPageToken).Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("unable to list YouTube playlists: %w", err)
+		}
+		for _, playlist := range response.Items {
+			if playlist.Snippet != nil && playlist.Snippet.Title == title {
+				fmt.Printf("YouTube playlist already exists: %s (ID: %s)\n", title, playlist.Id)
+				return playlist.Id, nil
+			}
+		}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+
+	playlist, err := service.Playlists.Insert([]string{"snippet", "status"}, &youtube.Playlist{
+		Snippet: &youtube.PlaylistSnippet{Title: title},
+		Status:  &youtube.PlaylistStatus{PrivacyStatus: "private"},
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to create YouTube playlist '%s': %w", title, err)
+	}
+	fmt.Printf("Created YouTube playlist: %s (ID: %s)\n", title, playlist.Id)
+	return playlist.Id, nil
+}
+
+// PlaylistVideoIDs returns the IDs of the videos already in a playlist.
+func PlaylistVideoIDs(ctx context.Context, service *youtube.Service, playlistID string) (map[string]bool, error) {
+	videoIDs := make(map[string]bool)
+	nextPageToken := ""
+	for {
+		response, err := service.PlaylistItems.List([]string{"snippet"}).PlaylistId(playlistID).
+			MaxResults(50).PageToken(nextPageToken).Fields("nextPageToken,items(snippet/resourceId/videoId)").Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range response.Items {
+			if item.Snippet != nil && item.Snippet.ResourceId != nil {
+				videoIDs[item.Snippet.ResourceId.VideoId] = true
+			}
+		}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			return videoIDs, nil
+		}
+	}
+}
+
+// AddVideoToPlaylist appends a video to the end of a playlist.
+func AddVideoToPlaylist(ctx context.Context, service *youtube.Service, playlistID, videoID string) error {
+	_, err := service.PlaylistItems.Insert([]string{"snippet"}, &youtube.PlaylistItem{
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistID,
+			ResourceId: &youtube.ResourceId{Kind: "youtube#video", VideoId: videoID},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to add video %s to YouTube playlist: %w", videoID, err)
+	}
+	return nil
+}
+
+// IsQuotaExceeded reports whether err means the daily YouTube Data API quota is used up,
+// in which case further calls are pointless until it resets.
+func IsQuotaExceeded(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "quotaExceeded" || item.Reason == "dailyLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err means a playlist or video does not exist, which is also what
+// YouTube answers for private playlists read without OAuth.
+func IsNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,266 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// SourceKind tells what a YouTube source refers to.
+type SourceKind int
+
+const (
+	// PlaylistSource is a playlist, imported item by item.
+	PlaylistSource SourceKind = iota
+	// VideoSource is a single video, imported as one track.
+	VideoSource
+	// ChannelSource is a channel, imported through its uploads playlist.
+	ChannelSource
+)
+
+// Source is a parsed playlist ID, YouTube or YouTube Music link, or channel handle.
+type Source struct {
+	Kind SourceKind
+	// ID is the playlist, video or channel ID. Channels given by handle or user name have no ID.
+	ID string
+	// Handle is the channel handle without the leading "@".
+	Handle string
+	// Username is a legacy youtube.com/user/ name.
+	Username string
+}
+
+// String returns a readable form of the source for log messages.
+func (s Source) String() string {
+	switch {
+	case s.Kind == VideoSource:
+		return "video " + s.ID
+	case s.Handle != "":
+		return "channel @" + s.Handle
+	case s.Username != "":
+		return "channel user " + s.Username
+	case s.Kind == ChannelSource:
+		return "channel " + s.ID
+	}
+	return "playlist " + s.ID
+}
+
+// URL returns a youtube.com link to the source.
+func (s Source) URL() string {
+	switch {
+	case s.Kind == VideoSource:
+		return "https://www.youtube.com/watch?v=" + s.ID
+	case s.Handle != "":
+		return "https://www.youtube.com/@" + s.Handle
+	case s.Username != "":
+		return "https://www.youtube.com/user/" + s.Username
+	case s.Kind == ChannelSource:
+		return "https://www.youtube.com/channel/" + s.ID
+	}
+	return "https://www.youtube.com/playlist?list=" + s.ID
+}
+
+var (
+	// videoIDPattern matches the 11 character video IDs.
+	videoIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{11}$`)
+	// channelIDPattern matches channel IDs such as UCxxxxxxxxxxxxxxxxxxxxxx.
+	channelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+)
+
+// youtubeHosts are the hosts whose links ParseSource understands.
+var youtubeHosts = map[string]bool{
+	"youtube.com":       true,
+	"www.youtube.com":   true,
+	"m.youtube.com":     true,
+	"music.youtube.com": true,
+	"youtu.be":          true,
+}
+
+// ParseSource parses a playlist source. It accepts bare playlist and channel IDs, "@handle", and links to
+// playlists (playlist?list=, watch?v=...&list=), videos (watch?v=, youtu.be, shorts), channels
+// (/@handle, /channel/UC..., /user/name) on youtube.com and music.youtube.com.
+func ParseSource(input string) (Source, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return Source{}, fmt.Errorf("empty YouTube source")
+	}
+	if strings.HasPrefix(input, "@") {
+		return Source{Kind: ChannelSource, Handle: strings.TrimPrefix(input, "@")}, nil
+	}
+	if channelIDPattern.MatchString(input) {
+		return Source{Kind: ChannelSource, ID: input}, nil
+	}
+	if !strings.Contains(input, "/") {
+		// A bare ID, as PLAYLISTS always took
+		return Source{Kind: PlaylistSource, ID: input}, nil
+	}
+
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	link, err := url.Parse(input)
+	if err != nil {
+		return Source{}, fmt.Errorf("invalid YouTube link %q: %w", input, err)
+	}
+	host := strings.ToLower(link.Hostname())
+	if !youtubeHosts[host] {
+		return Source{}, fmt.Errorf("%q is not a YouTube link", input)
+	}
+
+	query := link.Query()
+	segments := strings.Split(strings.Trim(link.Path, "/"), "/")
+	videoID := query.Get("v")
+	if host == "youtu.be" {
+		videoID = segments[0]
+	}
+
+	// Mixes (RD...) are generated per viewer and cannot be read through the API, so a watch link
+	// inside a mix only imports its video
+	if list := query.Get("list"); list != "" && !(strings.HasPrefix(list, "RD") && videoID != "") {
+		return Source{Kind: PlaylistSource, ID: list}, nil
+	}
+	if videoID != "" {
+		return videoSource(videoID, input)
+	}
+
+	switch {
+	case strings.HasPrefix(segments[0], "@"):
+		return Source{Kind: ChannelSource, Handle: strings.TrimPrefix(segments[0], "@")}, nil
+	case len(segments) < 2:
+	case segments[0] == "shorts" || segments[0] == "embed" || segments[0] == "live":
+		return videoSource(segments[1], input)
+	case segments[0] == "channel" || (segments[0] == "browse" && channelIDPattern.MatchString(segments[1])):
+		return Source{Kind: ChannelSource, ID: segments[1]}, nil
+	case segments[0] == "browse" && strings.HasPrefix(segments[1], "VL"):
+		// YouTube Music shows playlists as browse/VL<playlist ID>
+		return Source{Kind: PlaylistSource, ID: strings.TrimPrefix(segments[1], "VL")}, nil
+	case segments[0] == "user":
+		return Source{Kind: ChannelSource, Username: segments[1]}, nil
+	case segments[0] == "c":
+		// Custom URLs have no API lookup; they are almost always the channel's handle as well
+		return Source{Kind: ChannelSource, Handle: segments[1]}, nil
+	}
+	return Source{}, fmt.Errorf("unsupported YouTube link %q", input)
+}
+
+func videoSource(videoID, input string) (Source, error) {
+	if !videoIDPattern.MatchString(videoID) {
+		return Source{}, fmt.Errorf("invalid video ID in %q", input)
+	}
+	return Source{Kind: VideoSource, ID: videoID}, nil
+}
+
+// FetchSourceItems fetches the items of any source: the playlist items of a playlist or of a
+// channel's uploads, or a single item for a video.
+func FetchSourceItems(ctx context.Context, service *youtube.Service, source Source) ([]*PlaylistItem, error) {
+	switch source.Kind {
+	case VideoSource:
+		return fetchVideoItem(ctx, service, source.ID)
+	case ChannelSource:
+		uploadsID, err := uploadsPlaylistID(ctx, service, source)
+		if err != nil {
+			return nil, err
+		}
+		return FetchPlaylistItems(ctx, service, uploadsID)
+	}
+	return FetchPlaylistItems(ctx, service, source.ID)
+}
+
+// uploadsPlaylistID looks up the playlist holding every upload of a channel.
+func uploadsPlaylistID(ctx context.Context, service *youtube.Service, source Source) (string, error) {
+	response, err := channelsCall(service, source, "contentDetails").
+		Fields("items(id,contentDetails/relatedPlaylists/uploads)").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to look up %s: %w", source, err)
+	}
+	if len(response.Items) == 0 || response.Items[0].ContentDetails == nil ||
+		response.Items[0].ContentDetails.RelatedPlaylists == nil || response.Items[0].ContentDetails.RelatedPlaylists.Uploads == "" {
+		return "", fmt.Errorf("%s not found", source)
+	}
+	return response.Items[0].ContentDetails.RelatedPlaylists.Uploads, nil
+}
+
+// channelsCall prepares a channels.list call for a channel given by ID, handle or user name.
+func channelsCall(service *youtube.Service, source Source, part string) *youtube.ChannelsListCall {
+	call := service.Channels.List([]string{part})
+	switch {
+	case source.ID != "":
+		return call.Id(source.ID)
+	case source.Handle != "":
+		return call.ForHandle(source.Handle)
+	}
+	return call.ForUsername(source.Username)
+}
+
+// fetchVideoItem fetches a single video as a playlist item, so it can be imported like one.
+func fetchVideoItem(ctx context.Context, service *youtube.Service, videoID string) ([]*PlaylistItem, error) {
+	response, err := service.Videos.List([]string{"snippet", "contentDetails"}).Id(videoID).
+		Fields("items(id,snippet(title,description,channelId,channelTitle),contentDetails/duration)").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Items) == 0 || response.Items[0].Snippet == nil {
+		return nil, fmt.Errorf("video %s not found", videoID)
+	}
+
+	video := response.Items[0]
+	item := &PlaylistItem{PlaylistItem: &youtube.PlaylistItem{
+		Snippet: &youtube.PlaylistItemSnippet{
+			Title:                  video.Snippet.Title,
+			Description:            video.Snippet.Description,
+			VideoOwnerChannelId:    video.Snippet.ChannelId,
+			VideoOwnerChannelTitle: video.Snippet.ChannelTitle,
+			ResourceId:             &youtube.ResourceId{Kind: "youtube#video", VideoId: video.Id},
+		},
+	}}
+	if video.ContentDetails != nil {
+		item.Duration, _ = ParseISODuration(video.ContentDetails.Duration)
+	}
+	items := []*PlaylistItem{item}
+	attachTracklists(ctx, service, items)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// specialPlaylistTitles are the titles of the per-user lists, which playlists.list does not return.
+var specialPlaylistTitles = map[string]string{"LL": "Liked videos", "WL": "Watch later"}
+
+// SourceTitle returns the title of a source as shown on YouTube: the playlist or video title,
+// or "<channel> uploads" for a channel.
+func SourceTitle(ctx context.Context, service *youtube.Service, source Source) (string, error) {
+	switch source.Kind {
+	case VideoSource:
+		response, err := service.Videos.List([]string{"snippet"}).Id(source.ID).Fields("items(snippet/title)").Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		if len(response.Items) > 0 && response.Items[0].Snippet != nil {
+			return response.Items[0].Snippet.Title, nil
+		}
+	case ChannelSource:
+		response, err := channelsCall(service, source, "snippet").Fields("items(snippet/title)").Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		if len(response.Items) > 0 && response.Items[0].Snippet != nil {
+			return response.Items[0].Snippet.Title + " uploads", nil
+		}
+	default:
+		if title, ok := specialPlaylistTitles[source.ID]; ok {
+			return title, nil
+		}
+		response, err := service.Playlists.List([]string{"snippet"}).Id(source.ID).Fields("items(snippet/title)").Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		if len(response.Items) > 0 && response.Items[0].Snippet != nil {
+			return response.Items[0].Snippet.Title, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found", source)
+}
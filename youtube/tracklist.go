@@ -0,0 +1,170 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"yt-spotify/songs"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	// MinMixDuration is the length from which a video is checked for a tracklist. Shorter
+	// videos with timestamps are usually single songs with chapters like "Intro" or "Chorus".
+	MinMixDuration = 10 * time.Minute
+
+	// minTracklistEntries is how many timestamped lines make a tracklist.
+	minTracklistEntries = 3
+
+	// tracklistComments is how many top comments are searched for a tracklist.
+	tracklistComments = 20
+
+	// maxFirstTrailingStart is how late the first timestamp of a tracklist with trailing
+	// timestamps may start. Later ones are usually song lengths, which also go up now and then.
+	maxFirstTrailingStart = 30 * time.Second
+)
+
+// TracklistTrack is one track of a mix or compilation video.
+type TracklistTrack struct {
+	// Start is the offset of the track in the video.
+	Start  time.Duration
+	Title  string
+	Artist string
+	// Unsplit is the whole line when Title and Artist come from a " by " split, see songs.Entry.
+	Unsplit string
+}
+
+var (
+	// leadingTimestamp matches lines such as "00:00 Artist - Title", "1. [01:02:03] Artist - Title"
+	// or "03:45 - Artist - Title".
+	leadingTimestamp = regexp.MustCompile(`^(?:\d{1,3}[.)]\s*)?[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*(?:[-–—|:.]\s+)?(.+)$`)
+	// trailingTimestamp matches lines such as "Artist - Title 03:45" or "1. Artist - Title (03:45)".
+	trailingTimestamp = regexp.MustCompile(`^(?:\d{1,3}[.)]\s*)?(.+?)\s*(?:[-–—|]\s*)?[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?$`)
+)
+
+// ParseTracklist finds a timestamped tracklist in a video description or comment. YouTube
+// builds chapters from the same description timestamps, so chapter titles are found as well.
+// It returns nil unless there are at least three timestamps in ascending order. Entries
+// without an artist, such as "Intro", are chapters rather than tracks and are skipped.
+func ParseTracklist(text string) []TracklistTrack {
+	var tracks []TracklistTrack
+	first := true
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		start, rest, trailing, ok := splitTimestamp(line)
+		if !ok {
+			continue
+		}
+		if first && trailing && start > maxFirstTrailingStart {
+			// A list of song lengths, not of offsets into the video
+			return nil
+		}
+		first = false
+		if len(tracks) > 0 && start < tracks[len(tracks)-1].Start {
+			// Timestamps running backwards are not a tracklist, e.g. song lengths
+			return nil
+		}
+
+		entry, ok := songs.ParseLine(rest)
+		if !ok || entry.Kind != songs.FreeText || entry.Artist == "" || isUnknownTrack(entry.Title) || isChapterTitle(entry.Title) {
+			continue
+		}
+		tracks = append(tracks, TracklistTrack{Start: start, Title: entry.Title, Artist: entry.Artist, Unsplit: entry.Unsplit})
+	}
+
+	if len(tracks) < minTracklistEntries {
+		return nil
+	}
+	return tracks
+}
+
+// splitTimestamp splits a line into its timestamp and the rest. trailing tells whether the
+// timestamp came after the track.
+func splitTimestamp(line string) (start time.Duration, rest string, trailing, ok bool) {
+	timestamp := ""
+	if matches := leadingTimestamp.FindStringSubmatch(line); matches != nil {
+		timestamp, rest = matches[1], matches[2]
+	} else if matches := trailingTimestamp.FindStringSubmatch(line); matches != nil {
+		timestamp, rest, trailing = matches[2], matches[1], true
+	} else {
+		return 0, "", false, false
+	}
+
+	for _, part := range strings.Split(timestamp, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, "", false, false
+		}
+		start = start*60 + time.Duration(n)
+	}
+	rest = strings.TrimSpace(rest)
+	return start * time.Second, rest, trailing, rest != ""
+}
+
+// isUnknownTrack reports whether a DJ mix lists the track as unidentified, as in "ID - ID".
+func isUnknownTrack(title string) bool {
+	return strings.EqualFold(title, "ID") || strings.EqualFold(title, "unknown")
+}
+
+// chapterTitles are generic chapter names that are never a track of their own.
+var chapterTitles = map[string]bool{"intro": true, "outro": true, "interlude": true}
+
+// isChapterTitle reports whether a title is a generic chapter name such as "Intro".
+func isChapterTitle(title string) bool {
+	return chapterTitles[strings.ToLower(strings.TrimSpace(title))]
+}
+
+// attachTracklists looks for tracklists in videos at least MinMixDuration long: first in the
+// description, then in the top comments, preferring the uploader's own (usually pinned) comment.
+func attachTracklists(ctx context.Context, service *youtube.Service, items []*PlaylistItem) {
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if item.Duration < MinMixDuration || item.Snippet == nil || item.Snippet.ResourceId == nil {
+			continue
+		}
+
+		item.Tracklist = ParseTracklist(item.Snippet.Description)
+		if item.Tracklist == nil {
+			item.Tracklist = commentTracklist(ctx, service, item.Snippet.ResourceId.VideoId, item.Snippet.VideoOwnerChannelId)
+		}
+		if item.Tracklist != nil {
+			fmt.Printf("Found a tracklist of %d tracks in '%s'\n", len(item.Tracklist), item.Snippet.Title)
+		}
+	}
+}
+
+// commentTracklist returns the tracklist from the top comments of a video. Comments that are
+// disabled or fail to load mean there is no tracklist.
+func commentTracklist(ctx context.Context, service *youtube.Service, videoID, ownerChannelID string) []TracklistTrack {
+	response, err := service.CommentThreads.List([]string{"snippet"}).VideoId(videoID).Order("relevance").
+		TextFormat("plainText").MaxResults(tracklistComments).
+		Fields("items(snippet/topLevelComment/snippet(textOriginal,authorChannelId))").Context(ctx).Do()
+	if err != nil {
+		return nil
+	}
+
+	var fromOthers []TracklistTrack
+	for _, thread := range response.Items {
+		if thread.Snippet == nil || thread.Snippet.TopLevelComment == nil || thread.Snippet.TopLevelComment.Snippet == nil {
+			continue
+		}
+		comment := thread.Snippet.TopLevelComment.Snippet
+		tracks := ParseTracklist(comment.TextOriginal)
+		if tracks == nil {
+			continue
+		}
+		if comment.AuthorChannelId != nil && comment.AuthorChannelId.Value == ownerChannelID {
+			return tracks
+		}
+		if fromOthers == nil {
+			fromOthers = tracks
+		}
+	}
+	return fromOthers
+}
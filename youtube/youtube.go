@@ -2,32 +2,53 @@ package youtube
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
+// maxVideosPerRequest is the number of IDs videos.list accepts at once.
+const maxVideosPerRequest = 50
+
+// PlaylistItem is a YouTube playlist item together with the length of its video.
+type PlaylistItem struct {
+	*youtube.PlaylistItem
+	// Duration is zero when the video is unavailable, e.g. private or deleted.
+	Duration time.Duration
+	// Tracklist lists the tracks of a mix or compilation video, nil for single songs.
+	Tracklist []TracklistTrack
+}
+
 // NewService creates a new YouTube service.
-func NewService(apiKey string) (*youtube.Service, error) {
-	service, err := youtube.NewService(context.Background(), option.WithAPIKey(apiKey))
+func NewService(ctx context.Context, apiKey string) (*youtube.Service, error) {
+	service, err := youtube.NewService(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, err
 	}
 	return service, nil
 }
 
-// FetchPlaylistItems fetches items from a YouTube playlist.
-func FetchPlaylistItems(service *youtube.Service, playlistID string) ([]*youtube.PlaylistItem, error) {
-	var items []*youtube.PlaylistItem
+// FetchPlaylistItems fetches items from a YouTube playlist along with their video durations
+// and, for mixes, their tracklists.
+func FetchPlaylistItems(ctx context.Context, service *youtube.Service, playlistID string) ([]*PlaylistItem, error) {
+	var items []*PlaylistItem
 	nextPageToken := ""
 
 	for {
 		call := service.PlaylistItems.List([]string{"snippet"}).PlaylistId(playlistID).MaxResults(50).PageToken(nextPageToken)
-		response, err := call.Do()
+		response, err := call.Context(ctx).Do()
 		if err != nil {
 			return nil, err
 		}
 
-		items = append(items, response.Items...)
+		for _, item := range response.Items {
+			items = append(items, &PlaylistItem{PlaylistItem: item})
+		}
 		nextPageToken = response.NextPageToken
 
 		if nextPageToken == "" {
@@ -35,5 +56,87 @@ func FetchPlaylistItems(service *youtube.Service, playlistID string) ([]*youtube
 		}
 	}
 
+	// Durations are optional, the matcher treats a zero length as unknown
+	if err := attachDurations(ctx, service, items); err != nil && ctx.Err() == nil {
+		log.Printf("Continuing without video durations for playlist %s: %v", playlistID, err)
+	}
+	attachTracklists(ctx, service, items)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return items, nil
 }
+
+// attachDurations looks up the video lengths of the items with batched videos.list calls.
+func attachDurations(ctx context.Context, service *youtube.Service, items []*PlaylistItem) error {
+	var videoIDs []string
+	for _, item := range items {
+		if item.Snippet != nil && item.Snippet.ResourceId != nil && item.Snippet.ResourceId.VideoId != "" {
+			videoIDs = append(videoIDs, item.Snippet.ResourceId.VideoId)
+		}
+	}
+
+	// Keep the durations fetched before a failing batch
+	var fetchErr error
+	durations := make(map[string]time.Duration, len(videoIDs))
+	for start := 0; start < len(videoIDs); start += maxVideosPerRequest {
+		end := min(start+maxVideosPerRequest, len(videoIDs))
+		response, err := service.Videos.List([]string{"contentDetails"}).Id(videoIDs[start:end]...).
+			Fields("items(id,contentDetails/duration)").Context(ctx).Do()
+		if err != nil {
+			fetchErr = fmt.Errorf("unable to fetch video durations: %w", err)
+			break
+		}
+
+		for _, video := range response.Items {
+			if video.ContentDetails == nil {
+				continue
+			}
+			duration, err := ParseISODuration(video.ContentDetails.Duration)
+			if err != nil {
+				continue
+			}
+			durations[video.Id] = duration
+		}
+	}
+
+	for _, item := range items {
+		if item.Snippet != nil && item.Snippet.ResourceId != nil {
+			item.Duration = durations[item.Snippet.ResourceId.VideoId]
+		}
+	}
+	return fetchErr
+}
+
+// isoDuration matches the ISO-8601 durations YouTube uses, e.g. PT4M13S or P1DT2H.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISODuration parses an ISO-8601 duration such as "PT1H2M3S".
+func ParseISODuration(value string) (time.Duration, error) {
+	matches := isoDuration.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	var duration time.Duration
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q: %w", value, err)
+		}
+		duration += time.Duration(n) * unit
+	}
+	if matches[5] != "" {
+		seconds, err := strconv.ParseFloat(matches[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q: %w", value, err)
+		}
+		duration += time.Duration(seconds * float64(time.Second))
+	}
+	return duration, nil
+}
@@ -1,113 +1,280 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"sync"
-	"time"
 	"yt-spotify/config"
+	"yt-spotify/importer"
+	"yt-spotify/pipeline"
 	"yt-spotify/service"
 	"yt-spotify/spotify"
+	"yt-spotify/state"
 	"yt-spotify/utils"
 	"yt-spotify/youtube"
 
 	youtubeV3 "google.golang.org/api/youtube/v3"
 )
 
-func YouTubeToSpotify() {
+// YouTubeToSpotify imports the configured YouTube sources into Spotify. When ctx is cancelled,
+// e.g. by Ctrl-C, in-flight requests are aborted, the tracks matched so far are still added and
+// a summary of the partial run is printed.
+//
+// Progress is saved to the import state file, so a rerun skips the videos already added and
+// reuses earlier extractions and matches. fresh ignores the saved progress.
+func YouTubeToSpotify(ctx context.Context, fresh bool) {
 	appCtx := config.GetAppContext()
 	if len(appCtx.Playlists) == 0 {
-		fmt.Print("Enter YouTube Playlist ID: ")
+		fmt.Print("Enter YouTube playlist ID, or a playlist, video or channel link: ")
 		var playlistID string
 		fmt.Scanln(&playlistID)
-		appCtx.Playlists = append(appCtx.Playlists, playlistID)
+		appCtx.Playlists = append(appCtx.Playlists, config.PlaylistSource{ID: playlistID})
 	}
 
-	youtubeService, err := youtube.NewService(appCtx.YouTubeAPIKey)
+	store, err := state.Open(appCtx.ImportStateFile, fresh)
 	if err != nil {
-		log.Fatalf("Unable to create YouTube service: %v", err)
+		log.Fatalf("Unable to load import state, rerun with --fresh to start over: %v", err)
 	}
 
-	spotifyClient, err := spotify.Authenticate(appCtx.SpotifyClientID, appCtx.SpotifyClientSecret, appCtx.SpotifyRedirectURI)
-	if err != nil {
-		log.Fatalf("Unable to authenticate with Spotify: %v", err)
+	youtubeServices := newYouTubeServices(ctx, appCtx)
+
+	spotifyClient := authenticateSpotify(ctx, appCtx)
+	playlists := spotify.NewPlaylistRegistry(spotifyClient)
+
+	aiService := newAiService(ctx, appCtx)
+	matcher := spotify.NewMatcher(appCtx.MatchThreshold, appCtx.DurationTolerance)
+
+	// fetch -> LLM extraction -> Spotify search -> ordered writer, each stage with its own limit
+	// The writer is not cancelled with ctx, so the tracks matched before an interruption are still added
+	addTracks := func(ctx context.Context, playlistID string, uris []string) int {
+		return flushTracks(ctx, spotifyClient, playlistID, uris)
+	}
+	writer := importer.NewWriter(context.WithoutCancel(ctx), addTracks, store)
+	pipeline.Run(ctx, pipeline.Limits{Extractors: appCtx.ExtractWorkers, Searchers: appCtx.SearchWorkers},
+		func(emit func(*importer.Job) bool) {
+			for _, source := range appCtx.Playlists {
+				if !fetchYouTubePlaylist(ctx, youtubeServices[appCtx.PlaylistAuth(source)], playlists, store, source, appCtx, emit) {
+					return
+				}
+			}
+		},
+		func(job *importer.Job) *importer.Job {
+			if job.Imported() || job.Video.Extracted {
+				return job
+			}
+			tracks, final := extractTracks(ctx, aiService, job.Item)
+			job.Video.Tracks = tracks
+			// Fallbacks after a failed extraction are not saved, so the next run tries again
+			if final && ctx.Err() == nil {
+				job.Video.Extracted = true
+				job.Record(store, func(video *state.Video) {
+					video.Extracted, video.Tracks = true, tracks
+				})
+			}
+			return job
+		},
+		func(job *importer.Job) *importer.Job {
+			if job.Imported() {
+				return job
+			}
+			for i := range job.Video.Tracks {
+				if uri, ok := importer.MatchTrack(ctx, spotifyClient, matcher, job.Target.Index, &job.Video.Tracks[i]); ok {
+					job.URIs = append(job.URIs, uri)
+				}
+			}
+			if job.Video.Extracted {
+				tracks := append([]state.Track(nil), job.Video.Tracks...)
+				job.Record(store, func(video *state.Video) { video.Tracks = tracks })
+			}
+			return job
+		},
+		writer.Write,
+	)
+	// Add what was matched even when interrupted, so the work is not lost
+	writer.FlushAll()
+	if err := store.Save(); err != nil {
+		log.Printf("Unable to save import state to %s: %v", store.Path(), err)
 	}
 
-	var wg sync.WaitGroup
-	for _, playlistID := range appCtx.Playlists {
-		wg.Add(1)
-		playlistID := playlistID
-		go func() {
-			defer wg.Done()
-			processYouTubePlaylist(youtubeService, spotifyClient, playlistID, appCtx)
-		}()
-		time.Sleep(500 * time.Millisecond)
+	if ctx.Err() != nil {
+		fmt.Printf("Interrupted: processed %d YouTube items (%d already imported) and added %d tracks before stopping. Run again to continue.\n",
+			writer.Items, writer.Resumed, writer.Added)
+		return
 	}
-	wg.Wait()
+	fmt.Printf("Done: processed %d YouTube items (%d already imported) and added %d tracks.\n",
+		writer.Items, writer.Resumed, writer.Added)
 }
 
-func processYouTubePlaylist(youtubeService *youtubeV3.Service, spotifyClient *http.Client, playlistID string, appCtx *config.AppContext) {
-	playlistItems, err := youtube.FetchPlaylistItems(youtubeService, playlistID)
+// newYouTubeServices creates a YouTube service for every access mode the configured playlists use,
+// so the OAuth sign-in happens once and before the playlists are processed concurrently.
+func newYouTubeServices(ctx context.Context, appCtx *config.AppContext) map[string]*youtubeV3.Service {
+	services := make(map[string]*youtubeV3.Service)
+	for _, source := range appCtx.Playlists {
+		mode := appCtx.PlaylistAuth(source)
+		if services[mode] != nil {
+			continue
+		}
+
+		if mode == config.AuthOAuth {
+			services[mode] = authenticateYouTube(ctx, appCtx)
+			continue
+		}
+		youtubeService, err := youtube.NewService(ctx, appCtx.YouTubeAPIKey)
+		if err != nil {
+			log.Fatalf("Unable to create YouTube service: %v", err)
+		}
+		services[mode] = youtubeService
+	}
+	return services
+}
+
+// fetchYouTubePlaylist fetches the items of a source, resolves its Spotify playlist and emits a
+// job per item. Sources that fail are logged and skipped. It returns false when the import was
+// cancelled and no further sources should be fetched.
+func fetchYouTubePlaylist(ctx context.Context, youtubeService *youtubeV3.Service, playlists *spotify.PlaylistRegistry, store *state.Store, playlistSource config.PlaylistSource, appCtx *config.AppContext, emit func(*importer.Job) bool) bool {
+	playlistID := playlistSource.ID
+	source, err := youtube.ParseSource(playlistID)
+	if err != nil {
+		log.Printf("Skipping %s: %v", playlistID, err)
+		return true
+	}
+
+	playlistItems, err := youtube.FetchSourceItems(ctx, youtubeService, source)
+	if ctx.Err() != nil {
+		return false
+	}
 	if err != nil {
 		log.Printf("Unable to fetch YouTube playlist items for %s: %v", playlistID, err)
-		return
+		if youtube.IsNotFound(err) && source.Kind == youtube.PlaylistSource {
+			log.Printf("Private playlists can only be read with OAuth, set {\"id\": \"%s\", \"auth\": \"oauth\"} in PLAYLISTS", source.ID)
+		}
+		return true
 	}
 
-	spotifyPlaylistID, err := spotify.CheckOrCreatePlaylist(spotifyClient, appCtx.PlayListsNameToSave)
+	playlistName, description := targetPlaylist(ctx, youtubeService, playlistSource, source, appCtx)
+	spotifyPlaylistID, err := playlists.PlaylistID(ctx, playlistName, description)
 	if err != nil {
 		log.Printf("Unable to find or create Spotify playlist for %s: %v", playlistID, err)
-		return
+		return true
 	}
 
-	var aiService service.AiService
+	playlistIndex, err := playlists.Index(ctx, spotifyPlaylistID)
+	if err != nil {
+		log.Printf("Unable to load Spotify playlist tracks for %s: %v", playlistID, err)
+		return true
+	}
+
+	target := &importer.Target{ID: spotifyPlaylistID, Index: playlistIndex}
+	for _, item := range playlistItems {
+		job := &importer.Job{Target: target, Item: item, Source: source.String()}
+		if item.Snippet != nil && item.Snippet.ResourceId != nil {
+			job.VideoID = item.Snippet.ResourceId.VideoId
+		}
+		if job.VideoID != "" {
+			job.Video = store.Video(job.Source, job.VideoID)
+		}
+		if !emit(job) {
+			return false
+		}
+	}
+	return true
+}
 
+// newAiService returns the configured LLM used to extract song and artist, or nil to use the raw metadata.
+func newAiService(ctx context.Context, appCtx *config.AppContext) service.AiService {
 	switch appCtx.ModelToUse {
 	case utils.MISTRAL:
 		mistralService, err := service.NewMistralService(appCtx)
 		if err != nil {
 			log.Printf("Error initializing Mistral Service: %v", err)
-		} else {
-			aiService = mistralService
+			return nil
 		}
+		return mistralService
 	case utils.OLLAMA:
 		ollamaService := service.NewOllamaService()
-		if ollamaService.IsOllamaAvailable() {
-			aiService = ollamaService
-		} else {
-			log.Println("Ollama API is not running. Falling back to raw metadata.")
+		if ollamaService.IsOllamaAvailable(ctx) {
+			return ollamaService
 		}
+		log.Println("Ollama API is not running. Falling back to raw metadata.")
 	default:
 		log.Println("No valid AI model selected. Using raw metadata.")
 	}
+	return nil
+}
 
-	for _, item := range playlistItems {
-		trackName := item.Snippet.Title
-		artistName := item.Snippet.VideoOwnerChannelTitle
-		description := item.Snippet.Description
-
-		// Use LLM
-		if aiService != nil {
-			extractedTrack, extractedArtist, err := aiService.ExtractSongArtist(trackName + " " + description)
-			if err == nil {
-				trackName = extractedTrack
-				artistName = extractedArtist
-			} else {
-				log.Printf("AI extraction failed, using default metadata: %v", err)
-			}
-		}
-		trackID, err := spotify.SearchTrack(spotifyClient, trackName, artistName)
-		if err != nil {
-			log.Printf("Unable to find track '%s' by '%s' on Spotify: %v", trackName, artistName, err)
-			continue
+// extractTracks returns the tracks to search for an item: one per track of a mix, or else the
+// song and artist extracted from the video title and description. final is false when the
+// extraction failed and the raw metadata was used instead.
+func extractTracks(ctx context.Context, aiService service.AiService, item *youtube.PlaylistItem) (tracks []state.Track, final bool) {
+	if len(item.Tracklist) > 0 {
+		fmt.Printf("Adding the %d tracks of mix '%s'\n", len(item.Tracklist), item.Snippet.Title)
+		tracks := make([]state.Track, 0, len(item.Tracklist))
+		for _, track := range item.Tracklist {
+			// Mixes often cut tracks short, so the segment length says little about the release
+			tracks = append(tracks, state.Track{Title: track.Title, Artist: track.Artist, Unsplit: track.Unsplit})
 		}
+		return tracks, true
+	}
 
-		err = spotify.AddTrackToPlaylist(spotifyClient, spotifyPlaylistID, trackID)
-		if err != nil {
-			log.Printf("Unable to add track '%s' to Spotify playlist: %v", trackName, err)
-			continue
+	trackName := item.Snippet.Title
+	artistName := item.Snippet.VideoOwnerChannelTitle
+	description := item.Snippet.Description
+
+	// Use LLM
+	final = true
+	if aiService != nil {
+		extractedTrack, extractedArtist, err := aiService.ExtractSongArtist(ctx, trackName+" "+description)
+		if err == nil {
+			trackName = extractedTrack
+			artistName = extractedArtist
+		} else {
+			log.Printf("AI extraction failed, using default metadata: %v", err)
+			final = false
 		}
+	}
+	return []state.Track{{Title: trackName, Artist: artistName, Duration: item.Duration}}, final
+}
+
+// targetPlaylist returns the name and description of the Spotify playlist a source is added to.
+// In the per-playlist mode it is the configured name or else the YouTube title, described with
+// the source URL; otherwise every source goes to PLAYLIST_NAME_TO_SAVE.
+func targetPlaylist(ctx context.Context, youtubeService *youtubeV3.Service, playlistSource config.PlaylistSource, source youtube.Source, appCtx *config.AppContext) (string, string) {
+	if appCtx.PlaylistMode != config.PlaylistModePerSource {
+		return appCtx.PlayListsNameToSave, ""
+	}
+
+	description := "Imported from " + source.URL()
+	if playlistSource.Name != "" {
+		return playlistSource.Name, description
+	}
+	title, err := youtube.SourceTitle(ctx, youtubeService, source)
+	if err != nil {
+		log.Printf("Unable to fetch the title of %s, naming the Spotify playlist after it: %v", source, err)
+		return source.String(), description
+	}
+	return title, description
+}
+
+// flushTracks adds the collected track URIs to the Spotify playlist in batches, reports the outcome
+// and returns how many tracks were added.
+func flushTracks(ctx context.Context, spotifyClient *http.Client, spotifyPlaylistID string, uris []string) int {
+	if len(uris) == 0 {
+		fmt.Println("No new tracks to add to Spotify playlist")
+		return 0
+	}
 
-		fmt.Printf("Added '%s' by '%s' to Spotify playlist\n", trackName, artistName)
+	results, snapshotID, err := spotify.AddTracksToPlaylist(ctx, spotifyClient, spotifyPlaylistID, uris)
+	added := 0
+	for _, result := range results {
+		if result.Err == nil {
+			added += len(result.URIs)
+		}
+	}
+	if err != nil {
+		log.Printf("Unable to add %d of %d tracks to Spotify playlist: %v", len(uris)-added, len(uris), err)
 	}
+
+	fmt.Printf("Added %d tracks to Spotify playlist (snapshot %s)\n", added, snapshotID)
+	return added
 }